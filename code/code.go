@@ -28,9 +28,9 @@ func Highlight(content, extension, syntaxTheme string) (string, error) {
 }
 
 // readFileContentCmd reads the content of the file.
-func readFileContentCmd(fileName, syntaxTheme string) tea.Cmd {
+func readFileContentCmd(fsys filesystem.FS, fileName, syntaxTheme string) tea.Cmd {
 	return func() tea.Msg {
-		content, err := filesystem.ReadFileContent(fileName)
+		content, err := filesystem.ReadFileContentFS(fsys, fileName)
 		if err != nil {
 			return errorMsg(err)
 		}
@@ -51,6 +51,7 @@ type Model struct {
 	Filename           string
 	HighlightedContent string
 	SyntaxTheme        string
+	FileSystem         filesystem.FS
 }
 
 // New creates a new instance of code.
@@ -61,6 +62,7 @@ func New(active bool) Model {
 		Viewport:    viewPort,
 		Active:      active,
 		SyntaxTheme: "dracula",
+		FileSystem:  filesystem.OSFS{},
 	}
 }
 
@@ -73,7 +75,12 @@ func (m Model) Init() tea.Cmd {
 func (m *Model) SetFileName(filename string) tea.Cmd {
 	m.Filename = filename
 
-	return readFileContentCmd(filename, m.SyntaxTheme)
+	return readFileContentCmd(m.FileSystem, filename, m.SyntaxTheme)
+}
+
+// SetFileSystem sets the backend files are read from.
+func (m *Model) SetFileSystem(fsys filesystem.FS) {
+	m.FileSystem = fsys
 }
 
 // SetIsActive sets if the bubble is currently active.
@@ -0,0 +1,507 @@
+// Package diskusage implements a disk usage bubble which scans a directory
+// in the background and shows the size of each of its entries, largest first.
+package diskusage
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/mistakenelf/teacup/filesystem"
+	"github.com/mistakenelf/teacup/filetree"
+	"github.com/muesli/reflow/truncate"
+)
+
+const (
+	// refreshInterval is how often the results of a running scan are shown.
+	refreshInterval = 100 * time.Millisecond
+
+	// barWidth is the number of cells of the bar drawn next to each entry.
+	barWidth = 20
+)
+
+var (
+	selectedItemStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("212")).Bold(true)
+	headerStyle       = lipgloss.NewStyle().Foreground(lipgloss.Color("214")).Bold(true)
+	detailsStyle      = lipgloss.NewStyle().Foreground(lipgloss.Color("245"))
+	barStyle          = lipgloss.NewStyle().Foreground(lipgloss.Color("62"))
+	errorStyle        = lipgloss.NewStyle().Foreground(lipgloss.Color("196"))
+)
+
+type scanStartedMsg struct {
+	scan *filesystem.UsageScan
+	seq  int
+}
+
+type scanTickMsg struct {
+	scan *filesystem.UsageScan
+}
+
+type errorMsg error
+
+// Model represents the properties of a disk usage bubble.
+type Model struct {
+	id         int
+	fsys       filesystem.FS
+	options    filesystem.UsageOptions
+	keyMap     KeyMap
+	scan       *filesystem.UsageScan
+	scanSeq    int
+	directory  string
+	total      filesystem.UsageEntry
+	entries    []filesystem.UsageEntry
+	cursor     int
+	min        int
+	returnPath string
+	sizeFormat filetree.SizeFormat
+	err        error
+	width      int
+	height     int
+}
+
+// New creates a new instance of a disk usage bubble.
+func New() Model {
+	return Model{
+		id:     nextID(),
+		fsys:   filesystem.OSFS{},
+		keyMap: DefaultKeyMap(),
+	}
+}
+
+// resolvePath returns the absolute path of a directory, relative names are resolved
+// against the working directory for the local disk and the root for other backends.
+func resolvePath(fsys filesystem.FS, name string) (string, error) {
+	if name == filesystem.HomeDirectory {
+		return filesystem.GetHomeDirectory()
+	}
+
+	if filesystem.IsOS(fsys) {
+		return filepath.Abs(name)
+	}
+
+	return filepath.Join(filesystem.RootDirectory, name), nil
+}
+
+// isWithin reports if a directory is the same as or below root.
+func isWithin(root, directory string) bool {
+	if directory == "" {
+		return false
+	}
+
+	rel, err := filepath.Rel(root, directory)
+
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// startScanCmd starts scanning a directory, seq tells it apart from scans started before it.
+func startScanCmd(fsys filesystem.FS, name string, options filesystem.UsageOptions, seq int) tea.Cmd {
+	return func() tea.Msg {
+		name, err := resolvePath(fsys, name)
+		if err != nil {
+			return errorMsg(err)
+		}
+
+		return scanStartedMsg{scan: filesystem.ScanUsageFS(fsys, name, options), seq: seq}
+	}
+}
+
+// tickCmd shows the results of a running scan after a while.
+func tickCmd(scan *filesystem.UsageScan) tea.Cmd {
+	return tea.Tick(refreshInterval, func(time.Time) tea.Msg {
+		return scanTickMsg{scan: scan}
+	})
+}
+
+// Init scans the working directory.
+func (m Model) Init() tea.Cmd {
+	return m.routeCmd(startScanCmd(m.fsys, filesystem.CurrentDirectory, m.options, m.scanSeq))
+}
+
+// Scan stops the running scan and scans a directory.
+func (m *Model) Scan(name string) tea.Cmd {
+	return m.routeCmd(m.startScan(name))
+}
+
+// startScan stops the running scan and scans a directory, the messages
+// of the returned command are not yet tagged with the id of the model.
+func (m *Model) startScan(name string) tea.Cmd {
+	m.Cancel()
+
+	// Scans started before this one are ignored once they start.
+	m.scanSeq++
+
+	return startScanCmd(m.fsys, name, m.options, m.scanSeq)
+}
+
+// Cancel stops the running scan, keeping the sizes read so far.
+func (m *Model) Cancel() {
+	if m.scan != nil {
+		m.scan.Cancel()
+	}
+}
+
+// Close stops the running scan, it should be called once the bubble is no longer in use.
+func (m *Model) Close() {
+	m.Cancel()
+}
+
+// IsScanning reports if a scan is running.
+func (m Model) IsScanning() bool {
+	return m.scan != nil && !m.scan.IsDone()
+}
+
+// SetFileSystem sets the backend which is scanned, it applies to the next scan.
+func (m *Model) SetFileSystem(fsys filesystem.FS) {
+	m.fsys = fsys
+}
+
+// SetWorkers sets the number of directories read at the same time, the
+// number of CPUs is used when it is zero. It applies to the next scan.
+func (m *Model) SetWorkers(workers int) {
+	m.options.Workers = workers
+}
+
+// SetOneFileSystem sets if directories on other filesystems than the scanned
+// directory, such as mount points, are skipped. It applies to the next scan.
+func (m *Model) SetOneFileSystem(oneFileSystem bool) {
+	m.options.OneFileSystem = oneFileSystem
+}
+
+// SetApparentSize sets if the size of the content of files is shown instead of
+// the space allocated for them on disk. It applies to the next scan.
+func (m *Model) SetApparentSize(apparentSize bool) {
+	m.options.ApparentSize = apparentSize
+}
+
+// SetSizeFormat sets the units used to render sizes.
+func (m *Model) SetSizeFormat(sizeFormat filetree.SizeFormat) {
+	m.sizeFormat = sizeFormat
+}
+
+// SetKeyMap replaces the key bindings.
+func (m *Model) SetKeyMap(keyMap KeyMap) {
+	m.keyMap = keyMap
+}
+
+// KeyMap returns the key bindings.
+func (m Model) KeyMap() KeyMap {
+	return m.keyMap
+}
+
+// ShortHelp returns the most common bindings.
+func (m Model) ShortHelp() []key.Binding {
+	return m.keyMap.ShortHelp()
+}
+
+// FullHelp returns every binding, grouped into columns.
+func (m Model) FullHelp() [][]key.Binding {
+	return m.keyMap.FullHelp()
+}
+
+// SetSize sets the size of the bubble.
+func (m *Model) SetSize(w, h int) {
+	m.width = w
+	m.height = h
+	m.setCursor(m.cursor)
+}
+
+// CurrentDirectory returns the directory which is shown.
+func (m Model) CurrentDirectory() string {
+	return m.directory
+}
+
+// SelectedEntry returns the highlighted entry.
+func (m Model) SelectedEntry() (filesystem.UsageEntry, bool) {
+	if m.cursor >= len(m.entries) {
+		return filesystem.UsageEntry{}, false
+	}
+
+	return m.entries[m.cursor], true
+}
+
+// listHeight returns the number of rows available for the entries.
+func (m Model) listHeight() int {
+	return max(m.height-1, 1)
+}
+
+// setCursor moves the cursor to the given index, scrolling the
+// visible window so the cursor stays on screen.
+func (m *Model) setCursor(index int) {
+	m.cursor = max(min(index, len(m.entries)-1), 0)
+
+	if m.cursor < m.min {
+		m.min = m.cursor
+	}
+
+	if m.cursor >= m.min+m.listHeight() {
+		m.min = m.cursor - m.listHeight() + 1
+	}
+}
+
+// refresh reads the entries of the shown directory from the scan, keeping
+// the highlighted entry selected as entries move while sizes grow.
+func (m *Model) refresh() {
+	selectedPath := m.returnPath
+	if entry, ok := m.SelectedEntry(); ok && selectedPath == "" {
+		selectedPath = entry.Path
+	}
+
+	m.total, _ = m.scan.Entry(m.directory)
+	m.entries, _ = m.scan.Entries(m.directory)
+	m.setCursor(m.cursor)
+
+	for i, entry := range m.entries {
+		if entry.Path == selectedPath {
+			m.setCursor(i)
+			m.returnPath = ""
+
+			break
+		}
+	}
+}
+
+// showDirectory shows the entries of a directory found by the scan.
+func (m *Model) showDirectory(directory string) {
+	m.directory = directory
+	m.entries = nil
+	m.cursor = 0
+	m.min = 0
+	m.refresh()
+}
+
+// Update handles updating the UI of a disk usage bubble.
+func (m Model) Update(msg tea.Msg) (Model, tea.Cmd) {
+	// Messages of other models are ignored.
+	if routed, ok := msg.(routedMsg); ok {
+		if routed.id != m.id {
+			return m, nil
+		}
+
+		msg = routed.msg
+	}
+
+	m, cmd := m.update(msg)
+
+	return m, m.routeCmd(cmd)
+}
+
+// update handles a message, the messages of the returned command are tagged by Update.
+func (m Model) update(msg tea.Msg) (Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case scanStartedMsg:
+		if msg.seq != m.scanSeq {
+			msg.scan.Cancel()
+
+			return m, nil
+		}
+
+		m.Cancel()
+		m.scan = msg.scan
+		m.err = nil
+
+		// Rescanning stays in the directory which is shown, on the same entry.
+		directory := msg.scan.Root()
+		if isWithin(directory, m.directory) {
+			directory = m.directory
+
+			if entry, ok := m.SelectedEntry(); ok {
+				m.returnPath = entry.Path
+			}
+		}
+
+		m.showDirectory(directory)
+
+		return m, tickCmd(m.scan)
+	case scanTickMsg:
+		if msg.scan != m.scan {
+			return m, nil
+		}
+
+		// The results are read once more after the scan finished.
+		done := m.scan.IsDone()
+		m.refresh()
+
+		if done {
+			m.returnPath = ""
+
+			return m, nil
+		}
+
+		return m, tickCmd(m.scan)
+	case errorMsg:
+		m.err = msg
+	case tea.WindowSizeMsg:
+		m.SetSize(msg.Width, msg.Height)
+	case tea.KeyMsg:
+		return m.updateKey(msg)
+	}
+
+	return m, nil
+}
+
+// updateKey handles key presses.
+func (m Model) updateKey(msg tea.KeyMsg) (Model, tea.Cmd) {
+	switch {
+	case key.Matches(msg, m.keyMap.Down):
+		m.setCursor(m.cursor + 1)
+	case key.Matches(msg, m.keyMap.Up):
+		m.setCursor(m.cursor - 1)
+	case key.Matches(msg, m.keyMap.PageDown):
+		m.setCursor(m.cursor + m.listHeight())
+	case key.Matches(msg, m.keyMap.PageUp):
+		m.setCursor(m.cursor - m.listHeight())
+	case key.Matches(msg, m.keyMap.Top):
+		m.setCursor(0)
+	case key.Matches(msg, m.keyMap.Bottom):
+		m.setCursor(len(m.entries) - 1)
+	case key.Matches(msg, m.keyMap.Open):
+		entry, ok := m.SelectedEntry()
+		if !ok || !entry.IsDir || m.scan == nil {
+			break
+		}
+
+		// Directories on other filesystems were not read.
+		if _, ok := m.scan.Entry(entry.Path); ok {
+			m.showDirectory(entry.Path)
+		}
+	case key.Matches(msg, m.keyMap.Back):
+		if m.scan == nil || m.directory == m.scan.Root() {
+			break
+		}
+
+		m.returnPath = m.directory
+		m.showDirectory(filepath.Dir(m.directory))
+	case key.Matches(msg, m.keyMap.Rescan):
+		if m.scan != nil {
+			return m, m.startScan(m.scan.Root())
+		}
+	case key.Matches(msg, m.keyMap.Cancel):
+		m.Cancel()
+	}
+
+	return m, nil
+}
+
+// formatSize renders a size in the configured units.
+func (m Model) formatSize(size int64) string {
+	return filetree.FormatSize(size, m.sizeFormat)
+}
+
+// header returns the line shown above the entries.
+func (m Model) header() string {
+	if m.err != nil {
+		return errorStyle.Render("Error: " + m.err.Error())
+	}
+
+	if m.scan == nil {
+		return detailsStyle.Render("Scanning…")
+	}
+
+	status := fmt.Sprintf("%s  %d items", m.formatSize(m.total.Size), m.total.Items)
+
+	switch {
+	case m.IsScanning():
+		status += "  scanning…"
+	case !m.total.Complete:
+		status += "  stopped"
+	}
+
+	if unreadable := m.scan.Errors(); unreadable > 0 {
+		status += fmt.Sprintf("  %d unreadable", unreadable)
+	}
+
+	header := headerStyle.Render(m.directory) + "  " + detailsStyle.Render(status)
+	if m.total.Err != nil {
+		header += "  " + errorStyle.Render(m.total.Err.Error())
+	}
+
+	return header
+}
+
+// indicator returns the mark shown before an entry which is not fully counted.
+func indicator(entry filesystem.UsageEntry) string {
+	switch {
+	case entry.Err != nil:
+		return "!"
+	case entry.OtherFileSystem:
+		return ">"
+	case entry.IsDir && !entry.Complete:
+		return "…"
+	}
+
+	return " "
+}
+
+// renderEntry renders an entry with its size, a bar relative to the largest
+// entry and its share of the directory.
+func (m Model) renderEntry(entry filesystem.UsageEntry, largest int64, selected bool) string {
+	filled := 0
+	if largest > 0 {
+		filled = int(entry.Size * barWidth / largest)
+	}
+
+	percent := 0.0
+	if m.total.Size > 0 {
+		percent = float64(entry.Size) * 100 / float64(m.total.Size)
+	}
+
+	name := entry.Name
+	if entry.IsDir {
+		name += "/"
+	}
+
+	bar := strings.Repeat("█", filled) + strings.Repeat("░", barWidth-filled)
+	size := fmt.Sprintf("%9s %5.1f%% ", m.formatSize(entry.Size), percent)
+
+	var row string
+	if selected {
+		row = selectedItemStyle.Render(size + "[" + bar + "] " + indicator(entry) + name)
+	} else {
+		row = size + "[" + barStyle.Render(bar) + "] " + detailsStyle.Render(indicator(entry)) + name
+	}
+
+	if m.width > 0 {
+		return truncate.String(row, uint(m.width))
+	}
+
+	return row
+}
+
+// View returns a string representation of the disk usage bubble.
+func (m Model) View() string {
+	var view strings.Builder
+
+	header := m.header()
+	if m.width > 0 {
+		header = truncate.String(header, uint(m.width))
+	}
+
+	view.WriteString(header)
+
+	// Entries are sorted by size so the largest is the first one.
+	var largest int64
+	if len(m.entries) > 0 {
+		largest = m.entries[0].Size
+	}
+
+	rows := 0
+	for i := m.min; i < len(m.entries) && rows < m.listHeight(); i++ {
+		view.WriteString("\n" + m.renderEntry(m.entries[i], largest, i == m.cursor))
+		rows++
+	}
+
+	if len(m.entries) == 0 && m.scan != nil && !m.IsScanning() {
+		view.WriteString("\n" + detailsStyle.Render("The directory is empty"))
+		rows++
+	}
+
+	for ; rows < m.listHeight(); rows++ {
+		view.WriteRune('\n')
+	}
+
+	return view.String()
+}
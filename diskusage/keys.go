@@ -0,0 +1,46 @@
+package diskusage
+
+import "github.com/charmbracelet/bubbles/key"
+
+// KeyMap defines the key bindings of the bubble. It implements help.KeyMap.
+type KeyMap struct {
+	Down     key.Binding
+	Up       key.Binding
+	PageDown key.Binding
+	PageUp   key.Binding
+	Top      key.Binding
+	Bottom   key.Binding
+	Open     key.Binding
+	Back     key.Binding
+	Rescan   key.Binding
+	Cancel   key.Binding
+}
+
+// DefaultKeyMap returns the default key bindings.
+func DefaultKeyMap() KeyMap {
+	return KeyMap{
+		Down:     key.NewBinding(key.WithKeys("j", "down", "ctrl+n"), key.WithHelp("j", "down")),
+		Up:       key.NewBinding(key.WithKeys("k", "up", "ctrl+p"), key.WithHelp("k", "up")),
+		PageDown: key.NewBinding(key.WithKeys("pgdown", "ctrl+f"), key.WithHelp("pgdn", "page down")),
+		PageUp:   key.NewBinding(key.WithKeys("pgup", "ctrl+b"), key.WithHelp("pgup", "page up")),
+		Top:      key.NewBinding(key.WithKeys("g", "home"), key.WithHelp("g", "top")),
+		Bottom:   key.NewBinding(key.WithKeys("G", "end"), key.WithHelp("G", "bottom")),
+		Open:     key.NewBinding(key.WithKeys("enter", "l", "right"), key.WithHelp("l", "open")),
+		Back:     key.NewBinding(key.WithKeys("backspace", "h", "left"), key.WithHelp("h", "back")),
+		Rescan:   key.NewBinding(key.WithKeys("r"), key.WithHelp("r", "rescan")),
+		Cancel:   key.NewBinding(key.WithKeys("esc"), key.WithHelp("esc", "stop scan")),
+	}
+}
+
+// ShortHelp returns the most common bindings.
+func (k KeyMap) ShortHelp() []key.Binding {
+	return []key.Binding{k.Up, k.Down, k.Open, k.Back, k.Rescan, k.Cancel}
+}
+
+// FullHelp returns every binding, grouped into columns.
+func (k KeyMap) FullHelp() [][]key.Binding {
+	return [][]key.Binding{
+		{k.Up, k.Down, k.PageUp, k.PageDown, k.Top, k.Bottom},
+		{k.Open, k.Back, k.Rescan, k.Cancel},
+	}
+}
@@ -0,0 +1,40 @@
+package diskusage
+
+import (
+	"sync/atomic"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// lastID is the id given to the most recently created model.
+var lastID atomic.Int64
+
+// nextID returns a unique id for a new model.
+func nextID() int {
+	return int(lastID.Add(1))
+}
+
+// routedMsg is a message for the model with the given id, so that
+// several models can be shown side by side in the same program.
+type routedMsg struct {
+	id  int
+	msg tea.Msg
+}
+
+// ID returns the unique id of the model.
+func (m Model) ID() int {
+	return m.id
+}
+
+// routeCmd tags the message returned by a command with the id of the model.
+func (m Model) routeCmd(cmd tea.Cmd) tea.Cmd {
+	if cmd == nil {
+		return nil
+	}
+
+	id := m.id
+
+	return func() tea.Msg {
+		return routedMsg{id: id, msg: cmd()}
+	}
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"log"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/mistakenelf/teacup/diskusage"
+)
+
+// model represents the properties of the UI.
+type model struct {
+	diskusage diskusage.Model
+}
+
+// New creates a new instance of the UI.
+func New() model {
+	diskUsage := diskusage.New()
+	diskUsage.SetOneFileSystem(true)
+
+	return model{
+		diskusage: diskUsage,
+	}
+}
+
+// Init intializes the UI.
+func (m model) Init() tea.Cmd {
+	return m.diskusage.Init()
+}
+
+// Update handles all UI interactions.
+func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	var (
+		cmd  tea.Cmd
+		cmds []tea.Cmd
+	)
+
+	switch msg := msg.(type) {
+	case tea.KeyMsg:
+		switch msg.String() {
+		case "ctrl+c", "q":
+			m.diskusage.Close()
+			cmds = append(cmds, tea.Quit)
+		}
+	}
+
+	m.diskusage, cmd = m.diskusage.Update(msg)
+	cmds = append(cmds, cmd)
+
+	return m, tea.Batch(cmds...)
+}
+
+// View returns a string representation of the UI.
+func (m model) View() string {
+	return m.diskusage.View()
+}
+
+func main() {
+	b := New()
+	p := tea.NewProgram(b, tea.WithAltScreen())
+
+	if _, err := p.Run(); err != nil {
+		log.Fatal(err)
+	}
+}
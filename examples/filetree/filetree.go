@@ -14,7 +14,9 @@ type model struct {
 
 // New creates a new instance of the UI.
 func New() model {
-	filetree := filetree.New()
+	// Bookmarks are kept between runs, they are only kept in memory by default.
+	bookmarksPath, _ := filetree.DefaultBookmarksPath()
+	filetree := filetree.New(filetree.WithBookmarks(filetree.NewBookmarks(bookmarksPath)))
 
 	return model{
 		filetree: filetree,
@@ -36,7 +38,7 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	switch msg := msg.(type) {
 	case tea.KeyMsg:
 		switch msg.String() {
-		case "ctrl+c", "esc", "q":
+		case "ctrl+c":
 			cmds = append(cmds, tea.Quit)
 		}
 	}
@@ -54,7 +56,7 @@ func (m model) View() string {
 
 func main() {
 	b := New()
-	p := tea.NewProgram(&b, tea.WithAltScreen())
+	p := tea.NewProgram(&b, tea.WithAltScreen(), tea.WithMouseCellMotion())
 
 	if _, err := p.Run(); err != nil {
 		log.Fatal(err)
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"log"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/mistakenelf/teacup/miller"
+)
+
+// model represents the properties of the UI.
+type model struct {
+	miller miller.Model
+}
+
+// New creates a new instance of the UI.
+func New() model {
+	miller := miller.New()
+
+	return model{
+		miller: miller,
+	}
+}
+
+// Init intializes the UI.
+func (m model) Init() tea.Cmd {
+	return m.miller.Init()
+}
+
+// Update handles all UI interactions.
+func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	var (
+		cmd  tea.Cmd
+		cmds []tea.Cmd
+	)
+
+	switch msg := msg.(type) {
+	case tea.KeyMsg:
+		switch msg.String() {
+		case "ctrl+c":
+			cmds = append(cmds, tea.Quit)
+		}
+	}
+
+	m.miller, cmd = m.miller.Update(msg)
+	cmds = append(cmds, cmd)
+
+	return m, tea.Batch(cmds...)
+}
+
+// View returns a string representation of the UI.
+func (m model) View() string {
+	return m.miller.View()
+}
+
+func main() {
+	b := New()
+	p := tea.NewProgram(&b, tea.WithAltScreen(), tea.WithMouseCellMotion())
+
+	if _, err := p.Run(); err != nil {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,396 @@
+package filesystem
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// IsArchive reports if a file is an archive which can be opened with OpenArchive.
+func IsArchive(name string) bool {
+	name = strings.ToLower(name)
+
+	for _, extension := range []string{".zip", ".tar", ".tar.gz", ".tgz"} {
+		if strings.HasSuffix(name, extension) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// isGzipped reports if a tar archive is compressed with gzip.
+func isGzipped(name string) bool {
+	name = strings.ToLower(name)
+
+	return strings.HasSuffix(name, ".tar.gz") || strings.HasSuffix(name, ".tgz")
+}
+
+// OpenArchive opens a zip or tar archive as a read only backend without extracting it.
+// The returned closer must be closed once the archive is no longer browsed.
+func OpenArchive(fsys FS, name string) (FS, io.Closer, error) {
+	if strings.HasSuffix(strings.ToLower(name), ".zip") {
+		reader, closer, err := openZip(fsys, name)
+		if err != nil {
+			return nil, nil, errors.Unwrap(err)
+		}
+
+		return NewIOFS(reader), closer, nil
+	}
+
+	tarFS, err := newTarFS(func() (io.ReadCloser, error) {
+		return openTar(fsys, name)
+	})
+	if err != nil {
+		return nil, nil, errors.Unwrap(err)
+	}
+
+	return NewIOFS(tarFS), tarFS, nil
+}
+
+// ExtractFS copies files or directories out of one backend, such as an archive,
+// into a directory of another backend. Directories are merged with ones which
+// already exist, files which already exist are not replaced.
+func ExtractFS(src FS, names []string, dst FS, dir string) error {
+	for _, name := range names {
+		if _, err := extractFS(src, name, dst, dir); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// extractFS copies a file or directory out of one backend into a directory of another,
+// returning the files and directories which it created. Only the outermost directory
+// created is returned, as everything within it was created along with it.
+func extractFS(src FS, name string, dst FS, dir string) ([]string, error) {
+	var created []string
+
+	root := filepath.Dir(name)
+	newDirectories := make(map[string]bool)
+
+	err := WalkDirFS(src, name, func(filePath string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		relPath, err := filepath.Rel(root, filePath)
+		if err != nil {
+			return err
+		}
+
+		output := filepath.Join(dir, relPath)
+
+		if entry.IsDir() {
+			if _, err := dst.Stat(output); err == nil {
+				return nil
+			}
+
+			if err := dst.Mkdir(output, os.ModePerm); err != nil {
+				return err
+			}
+
+			if !newDirectories[filepath.Dir(output)] {
+				created = append(created, output)
+			}
+
+			newDirectories[output] = true
+
+			return nil
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+
+		srcFile, err := src.Open(filePath)
+		if err != nil {
+			return err
+		}
+
+		defer srcFile.Close()
+
+		destFile, err := createNewFile(dst, output, info.Mode().Perm())
+		if err != nil {
+			return err
+		}
+
+		if !newDirectories[filepath.Dir(output)] {
+			created = append(created, output)
+		}
+
+		if _, err := io.Copy(destFile, srcFile); err != nil {
+			_ = destFile.Close()
+
+			return err
+		}
+
+		return destFile.Close()
+	})
+
+	return created, errors.Unwrap(err)
+}
+
+// tarStream is a decompressed tar archive which closes every underlying reader.
+type tarStream struct {
+	io.Reader
+	closers []io.Closer
+}
+
+// Close closes the underlying readers.
+func (s *tarStream) Close() error {
+	var err error
+
+	for i := len(s.closers) - 1; i >= 0; i-- {
+		if closeErr := s.closers[i].Close(); err == nil {
+			err = closeErr
+		}
+	}
+
+	return err
+}
+
+// openTar opens a tar archive, decompressing it when needed.
+func openTar(fsys FS, name string) (io.ReadCloser, error) {
+	file, err := fsys.Open(name)
+	if err != nil {
+		return nil, err
+	}
+
+	stream := &tarStream{Reader: file, closers: []io.Closer{file}}
+
+	if isGzipped(name) {
+		gzipReader, err := gzip.NewReader(file)
+		if err != nil {
+			_ = file.Close()
+
+			return nil, &fs.PathError{Op: "open", Path: name, Err: err}
+		}
+
+		stream.Reader = gzipReader
+		stream.closers = append(stream.closers, gzipReader)
+	}
+
+	return stream, nil
+}
+
+// tarFS is an io/fs.FS for a tar archive. Only the headers are kept in memory,
+// the contents of a file are streamed from the archive when it is opened.
+type tarFS struct {
+	open     func() (io.ReadCloser, error)
+	headers  map[string]*tar.Header
+	children map[string][]string
+}
+
+// newTarFS indexes the headers of a tar archive.
+func newTarFS(open func() (io.ReadCloser, error)) (*tarFS, error) {
+	stream, err := open()
+	if err != nil {
+		return nil, err
+	}
+
+	defer stream.Close()
+
+	t := &tarFS{
+		open:     open,
+		headers:  map[string]*tar.Header{".": {Name: ".", Typeflag: tar.TypeDir, Mode: 0o755}},
+		children: map[string][]string{},
+	}
+
+	reader := tar.NewReader(stream)
+
+	for {
+		header, err := reader.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+
+		if err != nil {
+			return nil, &fs.PathError{Op: "open", Path: ".", Err: err}
+		}
+
+		name, ok := tarName(header.Name)
+		if !ok {
+			continue
+		}
+
+		t.add(name, header)
+	}
+
+	for _, children := range t.children {
+		slices.Sort(children)
+	}
+
+	return t, nil
+}
+
+// tarName returns the path of an entry of a tar archive within it. Entries with
+// absolute names or which are outside of the archive are left out.
+func tarName(name string) (string, bool) {
+	name = path.Clean(name)
+
+	return name, name != "." && fs.ValidPath(name)
+}
+
+// add records a header along with any parent directories missing from the archive.
+func (t *tarFS) add(name string, header *tar.Header) {
+	if _, ok := t.headers[name]; !ok {
+		parent := path.Dir(name)
+		t.children[parent] = append(t.children[parent], name)
+
+		if _, ok := t.headers[parent]; !ok {
+			t.add(parent, &tar.Header{Name: parent, Typeflag: tar.TypeDir, Mode: 0o755})
+		}
+	}
+
+	t.headers[name] = header
+}
+
+// Open opens a file or directory in the archive.
+func (t *tarFS) Open(name string) (fs.File, error) {
+	header, ok := t.headers[name]
+	if !fs.ValidPath(name) || !ok {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+	}
+
+	if header.Typeflag == tar.TypeDir {
+		entries, err := t.ReadDir(name)
+		if err != nil {
+			return nil, err
+		}
+
+		return &tarDir{info: tarFileInfo{header.FileInfo(), name}, entries: entries}, nil
+	}
+
+	stream, err := t.open()
+	if err != nil {
+		return nil, err
+	}
+
+	reader := tar.NewReader(stream)
+
+	for {
+		current, err := reader.Next()
+		if err != nil {
+			_ = stream.Close()
+
+			if errors.Is(err, io.EOF) {
+				err = fs.ErrNotExist
+			}
+
+			return nil, &fs.PathError{Op: "open", Path: name, Err: err}
+		}
+
+		if currentName, ok := tarName(current.Name); ok && currentName == name {
+			return &tarFile{Reader: reader, closer: stream, info: tarFileInfo{current.FileInfo(), name}}, nil
+		}
+	}
+}
+
+// Stat returns information about a file or directory in the archive.
+func (t *tarFS) Stat(name string) (fs.FileInfo, error) {
+	header, ok := t.headers[name]
+	if !fs.ValidPath(name) || !ok {
+		return nil, &fs.PathError{Op: "stat", Path: name, Err: fs.ErrNotExist}
+	}
+
+	return tarFileInfo{header.FileInfo(), name}, nil
+}
+
+// ReadDir returns the entries of a directory in the archive sorted by name.
+func (t *tarFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	header, ok := t.headers[name]
+	if !fs.ValidPath(name) || !ok || header.Typeflag != tar.TypeDir {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: fs.ErrNotExist}
+	}
+
+	entries := make([]fs.DirEntry, 0, len(t.children[name]))
+	for _, child := range t.children[name] {
+		entries = append(entries, fs.FileInfoToDirEntry(tarFileInfo{t.headers[child].FileInfo(), child}))
+	}
+
+	return entries, nil
+}
+
+// Close releases the archive. Files are opened on demand so there is nothing to close.
+func (t *tarFS) Close() error {
+	return nil
+}
+
+// tarFileInfo describes an entry of a tar archive using the base of its cleaned name.
+type tarFileInfo struct {
+	fs.FileInfo
+	name string
+}
+
+// Name returns the base name of the entry.
+func (i tarFileInfo) Name() string {
+	return path.Base(i.name)
+}
+
+// tarFile is a file being streamed from a tar archive.
+type tarFile struct {
+	io.Reader
+	closer io.Closer
+	info   fs.FileInfo
+}
+
+// Stat returns information about the file.
+func (f *tarFile) Stat() (fs.FileInfo, error) {
+	return f.info, nil
+}
+
+// Close closes the underlying archive.
+func (f *tarFile) Close() error {
+	return f.closer.Close()
+}
+
+// tarDir is an open directory of a tar archive.
+type tarDir struct {
+	info    fs.FileInfo
+	entries []fs.DirEntry
+}
+
+// Stat returns information about the directory.
+func (d *tarDir) Stat() (fs.FileInfo, error) {
+	return d.info, nil
+}
+
+// Read always fails as directories can not be read.
+func (d *tarDir) Read([]byte) (int, error) {
+	return 0, &fs.PathError{Op: "read", Path: d.info.Name(), Err: fs.ErrInvalid}
+}
+
+// Close closes the directory.
+func (d *tarDir) Close() error {
+	return nil
+}
+
+// ReadDir reads the entries of the directory.
+func (d *tarDir) ReadDir(n int) ([]fs.DirEntry, error) {
+	if n <= 0 {
+		entries := d.entries
+		d.entries = nil
+
+		return entries, nil
+	}
+
+	if len(d.entries) == 0 {
+		return nil, io.EOF
+	}
+
+	n = min(n, len(d.entries))
+	entries := d.entries[:n]
+	d.entries = d.entries[n:]
+
+	return entries, nil
+}
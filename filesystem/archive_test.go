@@ -0,0 +1,256 @@
+package filesystem
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io"
+	"io/fs"
+	"reflect"
+	"testing"
+)
+
+// tarEntry is a file or directory written to a test archive.
+type tarEntry struct {
+	name    string
+	content string
+}
+
+// writeTar writes a tar archive into a MemFS, compressing it when gzipped is set.
+// Entries with a name ending in a slash are directories.
+func writeTar(t *testing.T, fsys *MemFS, name string, gzipped bool, entries []tarEntry) {
+	t.Helper()
+
+	var buffer bytes.Buffer
+
+	var output io.Writer = &buffer
+
+	var gzipWriter *gzip.Writer
+	if gzipped {
+		gzipWriter = gzip.NewWriter(&buffer)
+		output = gzipWriter
+	}
+
+	writer := tar.NewWriter(output)
+
+	for _, entry := range entries {
+		header := &tar.Header{Name: entry.name, Mode: 0o644, Size: int64(len(entry.content)), Typeflag: tar.TypeReg}
+		if entry.name[len(entry.name)-1] == '/' {
+			header.Mode, header.Size, header.Typeflag = 0o755, 0, tar.TypeDir
+		}
+
+		if err := writer.WriteHeader(header); err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := writer.Write([]byte(entry.content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if gzipWriter != nil {
+		if err := gzipWriter.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	writeMemFile(t, fsys, name, buffer.String())
+}
+
+// archiveTree returns every item in an archive in the same form as memTree.
+func archiveTree(t *testing.T, archive FS) map[string]string {
+	t.Helper()
+
+	tree := make(map[string]string)
+
+	err := WalkDirFS(archive, "/", func(name string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if entry.IsDir() {
+			tree[name] = "/"
+
+			return nil
+		}
+
+		data, err := archive.ReadFile(name)
+		tree[name] = string(data)
+
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return tree
+}
+
+func TestOpenArchiveTar(t *testing.T) {
+	entries := []tarEntry{
+		{name: "a/b/c.txt", content: "c"},
+		{name: "./top.txt", content: "top"},
+		{name: "empty/"},
+		{name: "../evil.txt", content: "evil"},
+		{name: "a/../../escape.txt", content: "escape"},
+		{name: "/etc/passwd", content: "root"},
+	}
+
+	want := map[string]string{
+		"/": "/", "/a": "/", "/a/b": "/", "/a/b/c.txt": "c", "/top.txt": "top", "/empty": "/",
+	}
+
+	for _, name := range []string{"/d/archive.tar", "/d/archive.tar.gz", "/d/archive.tgz", "/d/ARCHIVE.TGZ"} {
+		t.Run(name, func(t *testing.T) {
+			fsys := NewMemFS()
+			writeTar(t, fsys, name, isGzipped(name), entries)
+
+			if !IsArchive(name) {
+				t.Errorf("IsArchive(%q) = false", name)
+			}
+
+			archive, closer, err := OpenArchive(fsys, name)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			defer closer.Close()
+
+			if got := archiveTree(t, archive); !reflect.DeepEqual(got, want) {
+				t.Errorf("archive = %v, want %v", got, want)
+			}
+
+			// Directories missing from the archive are added with it.
+			info, err := archive.Stat("/a/b")
+			if err != nil || !info.IsDir() || info.Name() != "b" {
+				t.Errorf("Stat(/a/b) = %v, %v, want a directory named b", info, err)
+			}
+
+			if _, err := archive.Stat("/etc"); !errors.Is(err, fs.ErrNotExist) {
+				t.Errorf("Stat(/etc) = %v, want %v", err, fs.ErrNotExist)
+			}
+		})
+	}
+}
+
+func TestOpenArchiveZip(t *testing.T) {
+	fsys := NewMemFS()
+	writeMemFile(t, fsys, "/d/src/main.go", "package main")
+	writeMemFile(t, fsys, "/d/src/lib/lib.go", "package lib")
+	zipMemFile(t, fsys, "/d/src", "/d/src.zip")
+
+	archive, closer, err := OpenArchive(fsys, "/d/src.zip")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer closer.Close()
+
+	want := map[string]string{"/": "/", "/main.go": "package main", "/lib": "/", "/lib/lib.go": "package lib"}
+	if got := archiveTree(t, archive); !reflect.DeepEqual(got, want) {
+		t.Errorf("archive = %v, want %v", got, want)
+	}
+
+	if err := archive.WriteFile("/new.txt", nil, 0o644); err == nil {
+		t.Error("WriteFile() into an archive did not fail")
+	}
+}
+
+func TestOpenArchiveInvalid(t *testing.T) {
+	fsys := NewMemFS()
+	writeMemFile(t, fsys, "/d/broken.zip", "not a zip")
+	writeMemFile(t, fsys, "/d/broken.tgz", "not gzipped")
+
+	for _, name := range []string{"/d/broken.zip", "/d/broken.tgz", "/d/missing.tar"} {
+		if _, _, err := OpenArchive(fsys, name); err == nil {
+			t.Errorf("OpenArchive(%q) did not fail", name)
+		}
+	}
+
+	if err := UnzipFS(fsys, "/d/broken.zip"); err == nil {
+		t.Error("UnzipFS() of an invalid zip file did not fail")
+	}
+}
+
+func TestExtract(t *testing.T) {
+	src := NewMemFS()
+	writeMemFile(t, src, "/docs/guide/intro.md", "intro")
+	writeMemFile(t, src, "/docs/guide/setup.md", "setup")
+	writeMemFile(t, src, "/docs/readme.md", "readme")
+
+	tests := []struct {
+		name        string
+		existing    map[string]string
+		extract     string
+		wantCreated []string
+		wantReadme  string
+		wantErr     error
+	}{
+		{
+			name:        "new directory",
+			extract:     "/docs",
+			wantCreated: []string{"/out/docs"},
+			wantReadme:  "/out/docs/readme.md",
+		},
+		{
+			name:        "file",
+			extract:     "/docs/readme.md",
+			wantCreated: []string{"/out/readme.md"},
+			wantReadme:  "/out/readme.md",
+		},
+		{
+			name:        "merged with an existing directory",
+			existing:    map[string]string{"/out/docs/other.md": "other"},
+			extract:     "/docs",
+			wantCreated: []string{"/out/docs/guide", "/out/docs/readme.md"},
+			wantReadme:  "/out/docs/readme.md",
+		},
+		{
+			name:        "existing file is not replaced",
+			existing:    map[string]string{"/out/docs/readme.md": "mine"},
+			extract:     "/docs",
+			wantCreated: []string{"/out/docs/guide"},
+			wantErr:     fs.ErrExist,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dst := NewMemFS()
+
+			if err := dst.Mkdir("/out", 0o755); err != nil {
+				t.Fatal(err)
+			}
+
+			for name, content := range tt.existing {
+				writeMemFile(t, dst, name, content)
+			}
+
+			created, err := extractFS(src, tt.extract, dst, "/out")
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("extractFS() error = %v, want %v", err, tt.wantErr)
+			}
+
+			if !reflect.DeepEqual(created, tt.wantCreated) {
+				t.Errorf("extractFS() created %v, want %v", created, tt.wantCreated)
+			}
+
+			for name, content := range tt.existing {
+				if data, err := dst.ReadFile(name); err != nil || string(data) != content {
+					t.Errorf("existing file %s = %q, %v, want %q", name, data, err, content)
+				}
+			}
+
+			if tt.wantReadme != "" {
+				if data, err := dst.ReadFile(tt.wantReadme); err != nil || string(data) != "readme" {
+					t.Errorf("extracted file = %q, %v, want %q", data, err, "readme")
+				}
+			}
+		})
+	}
+}
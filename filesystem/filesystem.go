@@ -1,9 +1,11 @@
 // Package filesystem is a collection of various different filesystem
-// helper functions.
+// helper functions. Each helper has a variant suffixed with FS which
+// operates on any FS backend, the plain variants operate on the local disk.
 package filesystem
 
 import (
 	"archive/zip"
+	"bytes"
 	"errors"
 	"fmt"
 	"io"
@@ -22,23 +24,171 @@ const (
 	RootDirectory     = "/"
 )
 
+// maxOutputAttempts is the number of names tried for a copy or zip file
+// when items made within the same second already have its name.
+const maxOutputAttempts = 100
+
 // Different types of listings.
 const (
 	DirectoriesListingType = "directories"
 	FilesListingType       = "files"
 )
 
+// bufferedFile buffers writes to a file, writing them to the backend once closed.
+type bufferedFile struct {
+	bytes.Buffer
+	fsys FS
+	name string
+	perm fs.FileMode
+}
+
+// Close writes the buffered content to the backend.
+func (f *bufferedFile) Close() error {
+	return f.fsys.WriteFile(f.name, f.Bytes(), f.perm)
+}
+
+// createFile creates a file for writing, streaming directly to disk for the local
+// disk and buffering in memory for other backends.
+func createFile(fsys FS, name string, perm fs.FileMode) (io.WriteCloser, error) {
+	if IsOS(fsys) {
+		return os.OpenFile(filepath.Clean(name), os.O_CREATE|os.O_WRONLY|os.O_TRUNC, perm)
+	}
+
+	if err := fsys.WriteFile(name, nil, perm); err != nil {
+		return nil, err
+	}
+
+	return &bufferedFile{fsys: fsys, name: name, perm: perm}, nil
+}
+
+// createNewFile creates a file for writing in the same way as createFile,
+// failing with fs.ErrExist instead of replacing a file which already exists.
+func createNewFile(fsys FS, name string, perm fs.FileMode) (io.WriteCloser, error) {
+	if IsOS(fsys) {
+		return os.OpenFile(filepath.Clean(name), os.O_CREATE|os.O_WRONLY|os.O_EXCL, perm)
+	}
+
+	if _, err := fsys.Stat(name); err == nil {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrExist}
+	}
+
+	return createFile(fsys, name, perm)
+}
+
+// createOutput creates an item in dir named after stem and extension along with the
+// current time, adding a counter when an item made within the same second already has
+// the name. The item is created by create, which fails with fs.ErrExist when it exists.
+func createOutput(dir, stem, extension string, create func(name string) error) (string, error) {
+	timestamp := time.Now().Unix()
+	output := filepath.Join(dir, fmt.Sprintf("%s_%d%s", stem, timestamp, extension))
+
+	for attempt := 2; ; attempt++ {
+		err := create(output)
+		if !errors.Is(err, fs.ErrExist) {
+			return output, err
+		}
+
+		if attempt > maxOutputAttempts {
+			return "", &fs.PathError{Op: "create", Path: output, Err: fs.ErrExist}
+		}
+
+		output = filepath.Join(dir, fmt.Sprintf("%s_%d_%d%s", stem, timestamp, attempt, extension))
+	}
+}
+
+// WalkDirFS walks the file tree rooted at root, calling fn for each file or directory
+// in the same way as filepath.WalkDir.
+func WalkDirFS(fsys FS, root string, fn fs.WalkDirFunc) error {
+	info, err := fsys.Stat(root)
+	if err != nil {
+		err = fn(root, nil, err)
+	} else {
+		err = walkDir(fsys, root, fs.FileInfoToDirEntry(info), fn)
+	}
+
+	if errors.Is(err, filepath.SkipDir) || errors.Is(err, filepath.SkipAll) {
+		return nil
+	}
+
+	return err
+}
+
+// walkDir recursively walks a directory for WalkDirFS.
+func walkDir(fsys FS, name string, entry fs.DirEntry, fn fs.WalkDirFunc) error {
+	if err := fn(name, entry, nil); err != nil || !entry.IsDir() {
+		if errors.Is(err, filepath.SkipDir) && entry.IsDir() {
+			err = nil
+		}
+
+		return err
+	}
+
+	entries, err := fsys.ReadDir(name)
+	if err != nil {
+		err = fn(name, entry, err)
+		if err != nil {
+			if errors.Is(err, filepath.SkipDir) && entry.IsDir() {
+				err = nil
+			}
+
+			return err
+		}
+	}
+
+	for _, child := range entries {
+		if err := walkDir(fsys, filepath.Join(name, child.Name()), child, fn); err != nil {
+			if errors.Is(err, filepath.SkipDir) {
+				break
+			}
+
+			return err
+		}
+	}
+
+	return nil
+}
+
+// skipEntry skips an entry while walking, along with its
+// contents when it is a directory.
+func skipEntry(entry fs.DirEntry) error {
+	if entry.IsDir() {
+		return filepath.SkipDir
+	}
+
+	return nil
+}
+
 // RenameDirectoryItem renames a directory or files given a source and destination.
 func RenameDirectoryItem(src, dst string) error {
-	err := os.Rename(src, dst)
+	return RenameDirectoryItemFS(OSFS{}, src, dst)
+}
+
+// RenameDirectoryItemFS renames a directory or files given a source and destination,
+// an item which already exists at the destination is not replaced.
+func RenameDirectoryItemFS(fsys FS, src, dst string) error {
+	if dstInfo, err := Lstat(fsys, dst); err == nil && filepath.Clean(src) != filepath.Clean(dst) {
+		// Renames which only change the case of a name on a case insensitive
+		// filesystem find the source at the destination.
+		srcInfo, err := Lstat(fsys, src)
+		if err != nil || !os.SameFile(srcInfo, dstInfo) {
+			return &fs.PathError{Op: "rename", Path: dst, Err: fs.ErrExist}
+		}
+	}
+
+	err := fsys.Rename(src, dst)
 
 	return errors.Unwrap(err)
 }
 
 // CreateDirectory creates a new directory given a name.
 func CreateDirectory(name string) error {
-	if _, err := os.Stat(name); errors.Is(err, os.ErrNotExist) {
-		err := os.Mkdir(name, os.ModePerm)
+	return CreateDirectoryFS(OSFS{}, name)
+}
+
+// CreateDirectoryFS creates a new directory given a name.
+func CreateDirectoryFS(fsys FS, name string) error {
+	if _, err := fsys.Stat(name); errors.Is(err, fs.ErrNotExist) {
+		err := fsys.Mkdir(name, os.ModePerm)
 		if err != nil {
 			return errors.Unwrap(err)
 		}
@@ -49,9 +199,14 @@ func CreateDirectory(name string) error {
 
 // GetDirectoryListing returns a list of files and directories within a given directory.
 func GetDirectoryListing(dir string, showHidden bool) ([]fs.DirEntry, error) {
+	return GetDirectoryListingFS(OSFS{}, dir, showHidden)
+}
+
+// GetDirectoryListingFS returns a list of files and directories within a given directory.
+func GetDirectoryListingFS(fsys FS, dir string, showHidden bool) ([]fs.DirEntry, error) {
 	index := 0
 
-	files, err := os.ReadDir(dir)
+	files, err := fsys.ReadDir(dir)
 	if err != nil {
 		return nil, errors.Unwrap(err)
 	}
@@ -76,9 +231,14 @@ func GetDirectoryListing(dir string, showHidden bool) ([]fs.DirEntry, error) {
 
 // GetDirectoryListingByType returns a directory listing based on type (directories | files).
 func GetDirectoryListingByType(dir, listingType string, showHidden bool) ([]fs.DirEntry, error) {
+	return GetDirectoryListingByTypeFS(OSFS{}, dir, listingType, showHidden)
+}
+
+// GetDirectoryListingByTypeFS returns a directory listing based on type (directories | files).
+func GetDirectoryListingByTypeFS(fsys FS, dir, listingType string, showHidden bool) ([]fs.DirEntry, error) {
 	index := 0
 
-	files, err := os.ReadDir(dir)
+	files, err := fsys.ReadDir(dir)
 	if err != nil {
 		return nil, errors.Unwrap(err)
 	}
@@ -109,7 +269,12 @@ func GetDirectoryListingByType(dir, listingType string, showHidden bool) ([]fs.D
 
 // DeleteDirectory deletes a directory given a name.
 func DeleteDirectory(name string) error {
-	err := os.RemoveAll(name)
+	return DeleteDirectoryFS(OSFS{}, name)
+}
+
+// DeleteDirectoryFS deletes a directory given a name.
+func DeleteDirectoryFS(fsys FS, name string) error {
+	err := fsys.RemoveAll(name)
 
 	return errors.Unwrap(err)
 }
@@ -136,21 +301,36 @@ func GetWorkingDirectory() (string, error) {
 
 // DeleteFile deletes a file given a name.
 func DeleteFile(name string) error {
-	err := os.Remove(name)
+	return DeleteFileFS(OSFS{}, name)
+}
+
+// DeleteFileFS deletes a file given a name.
+func DeleteFileFS(fsys FS, name string) error {
+	err := fsys.Remove(name)
 
 	return errors.Unwrap(err)
 }
 
 // MoveDirectoryItem moves a file from one place to another.
 func MoveDirectoryItem(src, dst string) error {
-	err := os.Rename(src, dst)
+	return MoveDirectoryItemFS(OSFS{}, src, dst)
+}
+
+// MoveDirectoryItemFS moves a file from one place to another.
+func MoveDirectoryItemFS(fsys FS, src, dst string) error {
+	err := fsys.Rename(src, dst)
 
 	return errors.Unwrap(err)
 }
 
 // ReadFileContent returns the contents of a file given a name.
 func ReadFileContent(name string) (string, error) {
-	fileContent, err := os.ReadFile(filepath.Clean(name))
+	return ReadFileContentFS(OSFS{}, name)
+}
+
+// ReadFileContentFS returns the contents of a file given a name.
+func ReadFileContentFS(fsys FS, name string) (string, error) {
+	fileContent, err := fsys.ReadFile(filepath.Clean(name))
 	if err != nil {
 		return "", errors.Unwrap(err)
 	}
@@ -160,7 +340,12 @@ func ReadFileContent(name string) (string, error) {
 
 // CreateFile creates a file given a name.
 func CreateFile(name string) error {
-	f, err := os.Create(filepath.Clean(name))
+	return CreateFileFS(OSFS{}, name)
+}
+
+// CreateFileFS creates a file given a name.
+func CreateFileFS(fsys FS, name string) error {
+	f, err := createFile(fsys, filepath.Clean(name), 0o666)
 	if err != nil {
 		return errors.Unwrap(err)
 	}
@@ -172,158 +357,220 @@ func CreateFile(name string) error {
 	return errors.Unwrap(err)
 }
 
-// Zip zips a directory given a name.
-func Zip(name string) error {
+// Zip zips a directory or file given a name, writing the zip file into dir.
+func Zip(name, dir string) error {
+	return ZipFS(OSFS{}, name, dir)
+}
+
+// ZipFS zips a directory or file given a name, writing the zip file into dir.
+func ZipFS(fsys FS, name, dir string) error {
+	_, err := zipFS(fsys, name, dir)
+
+	return err
+}
+
+// zipFS zips a directory or file, returning the path of the zip file.
+func zipFS(fsys FS, name, dir string) (string, error) {
 	var splitName []string
-	var output string
+	var stem string
+	var newfile io.WriteCloser
 
-	srcFile, err := os.Open(filepath.Clean(name))
+	info, err := fsys.Stat(name)
 	if err != nil {
-		return errors.Unwrap(err)
+		return "", errors.Unwrap(err)
 	}
 
-	defer func() {
-		err = srcFile.Close()
-	}()
-
 	fileExtension := filepath.Ext(name)
-	splitFileName := strings.Split(name, "/")
-	fileName := splitFileName[len(splitFileName)-1]
+	fileName := filepath.Base(name)
 	switch {
 	case strings.HasPrefix(fileName, ".") && fileExtension != "" && fileExtension == fileName:
-		output = fmt.Sprintf("%s_%d.zip", fileName, time.Now().Unix())
+		stem = fileName
 	case strings.HasPrefix(fileName, ".") && fileExtension != "" && fileExtension != fileName:
 		splitName = strings.Split(fileName, ".")
-		output = fmt.Sprintf(".%s_%d.zip", splitName[1], time.Now().Unix())
+		stem = "." + splitName[1]
 	case fileExtension != "":
 		splitName = strings.Split(fileName, ".")
-		output = fmt.Sprintf("%s_%d.zip", splitName[0], time.Now().Unix())
+		stem = splitName[0]
 	default:
-		output = fmt.Sprintf("%s_%d.zip", fileName, time.Now().Unix())
+		stem = fileName
 	}
 
-	newfile, err := os.Create(filepath.Clean(output))
+	output, err := createOutput(dir, stem, ".zip", func(output string) (err error) {
+		newfile, err = createNewFile(fsys, output, 0o666)
+
+		return err
+	})
 	if err != nil {
-		return errors.Unwrap(err)
+		return "", errors.Unwrap(err)
 	}
 
-	defer func() {
-		err = newfile.Close()
-	}()
-
 	zipWriter := zip.NewWriter(newfile)
-	defer func() {
-		err = zipWriter.Close()
-	}()
 
-	info, err := os.Stat(name)
-	if err != nil {
-		return errors.Unwrap(err)
+	// The root is the directory entries are named relative to, for a
+	// single file this is the directory containing it.
+	root := name
+	if !info.IsDir() {
+		root = filepath.Dir(name)
 	}
 
-	if info.IsDir() {
-		err = filepath.Walk(name, func(filePath string, info os.FileInfo, err error) error {
-			if info.IsDir() {
-				return nil
-			}
-
-			if err != nil {
-				return errors.Unwrap(err)
-			}
+	err = WalkDirFS(fsys, name, func(filePath string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
 
-			relPath := strings.TrimPrefix(filePath, name)
-			zipFile, err := zipWriter.Create(relPath)
-			if err != nil {
-				return errors.Unwrap(err)
-			}
+		if entry.IsDir() {
+			return nil
+		}
 
-			fsFile, err := os.Open(filepath.Clean(filePath))
-			if err != nil {
-				return errors.Unwrap(err)
-			}
+		fileInfo, err := entry.Info()
+		if err != nil {
+			return err
+		}
 
-			_, err = io.Copy(zipFile, fsFile)
-			if err != nil {
-				return errors.Unwrap(err)
-			}
+		relPath, err := filepath.Rel(root, filePath)
+		if err != nil {
+			return err
+		}
 
-			return nil
-		})
-	} else {
-		var files []string
+		header, err := zip.FileInfoHeader(fileInfo)
+		if err != nil {
+			return err
+		}
 
-		// Walk the directory to get a list of files within it and append it to
-		// the array of files.
-		err := filepath.Walk(name, func(path string, f fs.FileInfo, err error) error {
-			if f.Name() != "." && !f.IsDir() {
-				files = append(files, path)
-			}
+		header.Name = filepath.ToSlash(relPath)
+		header.Method = zip.Deflate
 
-			return nil
-		})
+		writer, err := zipWriter.CreateHeader(header)
+		if err != nil {
+			return err
+		}
 
+		fsFile, err := fsys.Open(filePath)
 		if err != nil {
-			return errors.Unwrap(err)
+			return err
 		}
 
-		for _, file := range files {
-			zipfile, err := os.Open(filepath.Clean(file))
-			if err != nil {
-				return errors.Unwrap(err)
-			}
+		_, err = io.Copy(writer, fsFile)
+		if closeErr := fsFile.Close(); err == nil {
+			err = closeErr
+		}
 
-			defer func() {
-				err = zipfile.Close()
-			}()
+		return err
+	})
 
-			info, err := zipfile.Stat()
-			if err != nil {
-				return errors.Unwrap(err)
-			}
+	if closeErr := zipWriter.Close(); err == nil {
+		err = closeErr
+	}
 
-			header, err := zip.FileInfoHeader(info)
-			if err != nil {
-				return errors.Unwrap(err)
-			}
+	if closeErr := newfile.Close(); err == nil {
+		err = closeErr
+	}
 
-			header.Method = zip.Deflate
-			writer, err := zipWriter.CreateHeader(header)
-			if err != nil {
-				return errors.Unwrap(err)
-			}
+	return output, errors.Unwrap(err)
+}
 
-			_, err = io.Copy(writer, zipfile)
-			if err != nil {
-				return errors.Unwrap(err)
-			}
+// openZip opens a zip archive for reading.
+func openZip(fsys FS, name string) (*zip.Reader, io.Closer, error) {
+	file, err := fsys.Open(name)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		_ = file.Close()
+
+		return nil, nil, err
+	}
+
+	// Read the archive into memory when the backend can not seek within it.
+	readerAt, ok := file.(io.ReaderAt)
+	if !ok {
+		data, err := io.ReadAll(file)
+		if err != nil {
+			_ = file.Close()
+
+			return nil, nil, err
 		}
+
+		readerAt = bytes.NewReader(data)
 	}
 
-	err = zipWriter.Close()
+	reader, err := zip.NewReader(readerAt, info.Size())
 	if err != nil {
-		return errors.Unwrap(err)
+		_ = file.Close()
+
+		return nil, nil, &fs.PathError{Op: "open", Path: name, Err: err}
 	}
 
-	return errors.Unwrap(err)
+	return reader, file, nil
 }
 
 // Unzip unzips a directory given a name.
 func Unzip(name string) error {
+	return UnzipFS(OSFS{}, name)
+}
+
+// UnzipFS unzips a directory given a name.
+func UnzipFS(fsys FS, name string) error {
+	_, err := unzipFS(fsys, name)
+
+	return err
+}
+
+// unzipFS unzips a directory, returning the files and directories which it created.
+// Only the output directory is returned when it did not exist beforehand.
+func unzipFS(fsys FS, name string) ([]string, error) {
+	var created []string
 	var output string
 
-	reader, err := zip.OpenReader(name)
+	reader, closer, err := openZip(fsys, name)
 	if err != nil {
-		return errors.Unwrap(err)
+		return nil, errors.Unwrap(err)
 	}
 
 	defer func() {
-		err = reader.Close()
+		err = closer.Close()
 	}()
 
-	if strings.HasPrefix(name, ".") {
-		output = strings.Split(name, ".")[1]
+	// The archive is extracted next to itself so only the
+	// base name is used to work out the output directory.
+	fileName := filepath.Base(name)
+	if strings.HasPrefix(fileName, ".") {
+		output = strings.Split(fileName, ".")[1]
 	} else {
-		output = strings.Split(name, ".")[0]
+		output = strings.Split(fileName, ".")[0]
+	}
+
+	output = filepath.Join(filepath.Dir(name), output)
+
+	// Anything which did not exist is recorded as it is created, unless
+	// the whole output directory is new.
+	exists := func(path string) bool {
+		_, err := fsys.Stat(path)
+
+		return err == nil
+	}
+
+	outputExists := exists(output)
+	if !outputExists {
+		created = append(created, output)
+	}
+
+	record := func(path string) {
+		if !outputExists {
+			return
+		}
+
+		for dir := path; dir != output; dir = filepath.Dir(dir) {
+			if !exists(dir) {
+				path = dir
+			}
+		}
+
+		if !exists(path) {
+			created = append(created, path)
+		}
 	}
 
 	for _, file := range reader.File {
@@ -331,59 +578,74 @@ func Unzip(name string) error {
 		fpath := filepath.Join(output, archiveFile)
 
 		if !strings.HasPrefix(fpath, filepath.Clean(output)+string(os.PathSeparator)) {
-			return errors.Unwrap(err)
+			return created, fmt.Errorf("%s: illegal file path", archiveFile)
 		}
 
+		record(fpath)
+
 		if file.FileInfo().IsDir() {
-			err = os.MkdirAll(fpath, os.ModePerm)
+			err = fsys.MkdirAll(fpath, os.ModePerm)
 			if err != nil {
-				return errors.Unwrap(err)
+				return created, errors.Unwrap(err)
 			}
 
 			continue
 		}
 
-		if err = os.MkdirAll(filepath.Dir(fpath), os.ModePerm); err != nil {
-			return errors.Unwrap(err)
+		if err = fsys.MkdirAll(filepath.Dir(fpath), os.ModePerm); err != nil {
+			return created, errors.Unwrap(err)
 		}
 
-		outFile, err := os.OpenFile(filepath.Clean(fpath), os.O_WRONLY|os.O_CREATE|os.O_TRUNC, file.Mode())
+		outFile, err := createNewFile(fsys, fpath, file.Mode())
 		if err != nil {
-			return errors.Unwrap(err)
+			return created, errors.Unwrap(err)
 		}
 
 		outputFile, err := file.Open()
 		if err != nil {
-			return errors.Unwrap(err)
+			return created, errors.Unwrap(err)
 		}
 
 		_, err = io.Copy(outFile, outputFile)
 		if err != nil {
-			return errors.Unwrap(err)
+			return created, errors.Unwrap(err)
 		}
 
 		err = outFile.Close()
 		if err != nil {
-			return errors.Unwrap(err)
+			return created, errors.Unwrap(err)
 		}
 
 		err = outputFile.Close()
 		if err != nil {
-			return errors.Unwrap(err)
+			return created, errors.Unwrap(err)
 		}
 	}
 
-	return errors.Unwrap(err)
+	return created, errors.Unwrap(err)
+}
+
+// CopyFile copies a file given a name, writing the copy into dir.
+func CopyFile(name, dir string) error {
+	return CopyFileFS(OSFS{}, name, dir)
+}
+
+// CopyFileFS copies a file given a name, writing the copy into dir.
+func CopyFileFS(fsys FS, name, dir string) error {
+	_, err := copyFileFS(fsys, name, dir)
+
+	return err
 }
 
-// CopyFile copies a file given a name.
-func CopyFile(name string) error {
+// copyFileFS copies a file, returning the path of the copy.
+func copyFileFS(fsys FS, name, dir string) (string, error) {
 	var splitName []string
-	var output string
+	var stem, extension string
+	var destFile io.WriteCloser
 
-	srcFile, err := os.Open(filepath.Clean(name))
+	srcFile, err := fsys.Open(filepath.Clean(name))
 	if err != nil {
-		return errors.Unwrap(err)
+		return "", errors.Unwrap(err)
 	}
 
 	defer func() {
@@ -391,68 +653,120 @@ func CopyFile(name string) error {
 	}()
 
 	fileExtension := filepath.Ext(name)
-	splitFileName := strings.Split(name, "/")
-	fileName := splitFileName[len(splitFileName)-1]
+	fileName := filepath.Base(name)
 	switch {
 	case strings.HasPrefix(fileName, ".") && fileExtension != "" && fileExtension == fileName:
-		output = fmt.Sprintf("%s_%d", fileName, time.Now().Unix())
+		stem = fileName
 	case strings.HasPrefix(fileName, ".") && fileExtension != "" && fileExtension != fileName:
 		splitName = strings.Split(fileName, ".")
-		output = fmt.Sprintf(".%s_%d.%s", splitName[1], time.Now().Unix(), splitName[2])
+		stem, extension = "."+splitName[1], "."+splitName[2]
 	case fileExtension != "":
 		splitName = strings.Split(fileName, ".")
-		output = fmt.Sprintf("%s_%d.%s", splitName[0], time.Now().Unix(), splitName[1])
+		stem, extension = splitName[0], "."+splitName[1]
 	default:
-		output = fmt.Sprintf("%s_%d", fileName, time.Now().Unix())
+		stem = fileName
 	}
 
-	destFile, err := os.Create(filepath.Clean(output))
+	output, err := createOutput(dir, stem, extension, func(output string) (err error) {
+		destFile, err = createNewFile(fsys, output, 0o666)
+
+		return err
+	})
 	if err != nil {
-		return errors.Unwrap(err)
+		return "", errors.Unwrap(err)
 	}
 
-	defer func() {
-		err = destFile.Close()
-	}()
-
 	_, err = io.Copy(destFile, srcFile)
 	if err != nil {
-		return errors.Unwrap(err)
+		_ = destFile.Close()
+
+		return output, errors.Unwrap(err)
 	}
 
-	err = destFile.Sync()
+	err = destFile.Close()
 	if err != nil {
-		return errors.Unwrap(err)
+		return output, errors.Unwrap(err)
 	}
 
-	return errors.Unwrap(err)
+	return output, errors.Unwrap(err)
 }
 
-// CopyDirectory copies a directory given a name.
-func CopyDirectory(name string) error {
-	output := fmt.Sprintf("%s_%d", name, time.Now().Unix())
+// CopyDirectory copies a directory given a name, writing the copy into dir.
+func CopyDirectory(name, dir string) error {
+	return CopyDirectoryFS(OSFS{}, name, dir)
+}
 
-	err := filepath.Walk(name, func(path string, info os.FileInfo, err error) error {
-		relPath := strings.Replace(path, name, "", 1)
+// CopyDirectoryFS copies a directory given a name, writing the copy into dir.
+func CopyDirectoryFS(fsys FS, name, dir string) error {
+	_, err := copyDirectoryFS(fsys, name, dir)
 
-		if info.IsDir() {
-			return fmt.Errorf("%w", os.Mkdir(filepath.Join(output, relPath), os.ModePerm))
+	return err
+}
+
+// copyDirectoryFS copies a directory, returning the path of the copy.
+func copyDirectoryFS(fsys FS, name, dir string) (string, error) {
+	output, err := createOutput(dir, filepath.Base(name), "", func(output string) error {
+		return fsys.Mkdir(output, os.ModePerm)
+	})
+	if err != nil {
+		return "", errors.Unwrap(err)
+	}
+
+	err = WalkDirFS(fsys, name, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
 		}
 
-		var data, err1 = os.ReadFile(filepath.Join(filepath.Clean(name), filepath.Clean(relPath)))
-		if err1 != nil {
-			return errors.Unwrap(err)
+		relPath, err := filepath.Rel(name, path)
+		if err != nil {
+			return err
+		}
+
+		if relPath == "." && entry.IsDir() {
+			// The output directory was created above.
+			return nil
+		}
+
+		if entry.IsDir() {
+			return fsys.Mkdir(filepath.Join(output, relPath), os.ModePerm)
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+
+		data, err := fsys.ReadFile(filepath.Clean(path))
+		if err != nil {
+			return err
 		}
 
-		return fmt.Errorf("%w", os.WriteFile(filepath.Join(output, relPath), data, os.ModePerm))
+		return fsys.WriteFile(filepath.Join(output, relPath), data, info.Mode().Perm())
 	})
 
-	return errors.Unwrap(err)
+	return output, errors.Unwrap(err)
 }
 
 // GetDirectoryItemSize calculates the size of a directory or file.
 func GetDirectoryItemSize(path string) (int64, error) {
-	curFile, err := os.Stat(path)
+	return GetDirectoryItemSizeWithIgnorerFS(OSFS{}, path, nil)
+}
+
+// GetDirectoryItemSizeFS calculates the size of a directory or file.
+func GetDirectoryItemSizeFS(fsys FS, path string) (int64, error) {
+	return GetDirectoryItemSizeWithIgnorerFS(fsys, path, nil)
+}
+
+// GetDirectoryItemSizeWithIgnorer calculates the size of a directory or file,
+// leaving out files matched by ignorer when it is not nil.
+func GetDirectoryItemSizeWithIgnorer(path string, ignorer *Ignorer) (int64, error) {
+	return GetDirectoryItemSizeWithIgnorerFS(OSFS{}, path, ignorer)
+}
+
+// GetDirectoryItemSizeWithIgnorerFS calculates the size of a directory or file,
+// leaving out files matched by ignorer when it is not nil.
+func GetDirectoryItemSizeWithIgnorerFS(fsys FS, path string, ignorer *Ignorer) (int64, error) {
+	curFile, err := fsys.Stat(path)
 	if err != nil {
 		return 0, errors.Unwrap(err)
 	}
@@ -462,11 +776,15 @@ func GetDirectoryItemSize(path string) (int64, error) {
 	}
 
 	var size int64
-	err = filepath.WalkDir(path, func(path string, entry os.DirEntry, err error) error {
+	err = WalkDirFS(fsys, path, func(path string, entry fs.DirEntry, err error) error {
 		if err != nil {
 			return errors.Unwrap(err)
 		}
 
+		if ignorer != nil && ignorer.IsIgnored(path, entry.IsDir()) {
+			return skipEntry(entry)
+		}
+
 		fileInfo, err := entry.Info()
 		if err != nil {
 			return errors.Unwrap(err)
@@ -484,14 +802,35 @@ func GetDirectoryItemSize(path string) (int64, error) {
 
 // FindFilesByName returns files found based on a name.
 func FindFilesByName(name, dir string) ([]string, []fs.DirEntry, error) {
+	return FindFilesByNameWithIgnorerFS(OSFS{}, name, dir, nil)
+}
+
+// FindFilesByNameFS returns files found based on a name.
+func FindFilesByNameFS(fsys FS, name, dir string) ([]string, []fs.DirEntry, error) {
+	return FindFilesByNameWithIgnorerFS(fsys, name, dir, nil)
+}
+
+// FindFilesByNameWithIgnorer returns files found based on a name, leaving
+// out files matched by ignorer when it is not nil.
+func FindFilesByNameWithIgnorer(name, dir string, ignorer *Ignorer) ([]string, []fs.DirEntry, error) {
+	return FindFilesByNameWithIgnorerFS(OSFS{}, name, dir, ignorer)
+}
+
+// FindFilesByNameWithIgnorerFS returns files found based on a name, leaving
+// out files matched by ignorer when it is not nil.
+func FindFilesByNameWithIgnorerFS(fsys FS, name, dir string, ignorer *Ignorer) ([]string, []fs.DirEntry, error) {
 	var paths []string
 	var entries []fs.DirEntry
 
-	err := filepath.WalkDir(dir, func(path string, entry os.DirEntry, err error) error {
+	err := WalkDirFS(fsys, dir, func(path string, entry fs.DirEntry, err error) error {
 		if err != nil {
 			return filepath.SkipDir
 		}
 
+		if ignorer != nil && ignorer.IsIgnored(path, entry.IsDir()) {
+			return skipEntry(entry)
+		}
+
 		if strings.Contains(entry.Name(), name) {
 			paths = append(paths, path)
 			entries = append(entries, entry)
@@ -503,32 +842,14 @@ func FindFilesByName(name, dir string) ([]string, []fs.DirEntry, error) {
 	return paths, entries, errors.Unwrap(err)
 }
 
-// WriteToFile writes content to a file, overwriting content if it exists.
-func WriteToFile(path, content string) error {
-	file, err := os.OpenFile(filepath.Clean(path), os.O_CREATE|os.O_WRONLY|os.O_TRUNC, os.ModePerm)
-	if err != nil {
-		return errors.Unwrap(err)
-	}
-
-	workingDir, err := os.Getwd()
-	if err != nil {
-		return errors.Unwrap(err)
-	}
-
-	_, err = file.WriteString(fmt.Sprintf("%s\n", filepath.Join(workingDir, content)))
-	if err != nil {
-		err = file.Close()
-		if err != nil {
-			return errors.Unwrap(err)
-		}
-
-		return errors.Unwrap(err)
-	}
+// WriteToFile writes content resolved against dir to a file, overwriting content if it exists.
+func WriteToFile(path, content, dir string) error {
+	return WriteToFileFS(OSFS{}, path, content, dir)
+}
 
-	err = file.Close()
-	if err != nil {
-		return errors.Unwrap(err)
-	}
+// WriteToFileFS writes content resolved against dir to a file, overwriting content if it exists.
+func WriteToFileFS(fsys FS, path, content, dir string) error {
+	err := fsys.WriteFile(filepath.Clean(path), []byte(fmt.Sprintf("%s\n", filepath.Join(dir, content))), os.ModePerm)
 
 	return errors.Unwrap(err)
 }
@@ -0,0 +1,257 @@
+package filesystem
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// ErrReadOnly is returned when trying to modify a read only filesystem.
+var ErrReadOnly = errors.New("filesystem is read only")
+
+// FS is a filesystem backend which the helpers in this package and
+// the bubbles in teacup can browse and modify.
+type FS interface {
+	// ReadDir returns the entries of a directory sorted by name.
+	ReadDir(name string) ([]fs.DirEntry, error)
+
+	// Stat returns information about a file, following symbolic links.
+	Stat(name string) (fs.FileInfo, error)
+
+	// Open opens a file for reading.
+	Open(name string) (fs.File, error)
+
+	// ReadFile returns the contents of a file.
+	ReadFile(name string) ([]byte, error)
+
+	// WriteFile writes data to a file, creating it if it does not exist.
+	WriteFile(name string, data []byte, perm fs.FileMode) error
+
+	// Rename renames or moves a file or directory.
+	Rename(oldpath, newpath string) error
+
+	// Remove removes a file or an empty directory.
+	Remove(name string) error
+
+	// RemoveAll removes a file or a directory and everything it contains.
+	RemoveAll(name string) error
+
+	// Mkdir creates a directory.
+	Mkdir(name string, perm fs.FileMode) error
+
+	// MkdirAll creates a directory along with any missing parents.
+	MkdirAll(name string, perm fs.FileMode) error
+}
+
+// LinkFS is implemented by backends which support symbolic links.
+type LinkFS interface {
+	FS
+
+	// Lstat returns information about a file without following symbolic links.
+	Lstat(name string) (fs.FileInfo, error)
+
+	// ReadLink returns the destination of a symbolic link.
+	ReadLink(name string) (string, error)
+}
+
+// OSFS is a backend for the local disk.
+type OSFS struct{}
+
+// ReadDir returns the entries of a directory sorted by name.
+func (OSFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	return os.ReadDir(name)
+}
+
+// Stat returns information about a file, following symbolic links.
+func (OSFS) Stat(name string) (fs.FileInfo, error) {
+	return os.Stat(name)
+}
+
+// Lstat returns information about a file without following symbolic links.
+func (OSFS) Lstat(name string) (fs.FileInfo, error) {
+	return os.Lstat(name)
+}
+
+// ReadLink returns the destination of a symbolic link.
+func (OSFS) ReadLink(name string) (string, error) {
+	return os.Readlink(name)
+}
+
+// Open opens a file for reading.
+func (OSFS) Open(name string) (fs.File, error) {
+	return os.Open(filepath.Clean(name))
+}
+
+// ReadFile returns the contents of a file.
+func (OSFS) ReadFile(name string) ([]byte, error) {
+	return os.ReadFile(filepath.Clean(name))
+}
+
+// WriteFile writes data to a file, creating it if it does not exist.
+func (OSFS) WriteFile(name string, data []byte, perm fs.FileMode) error {
+	return os.WriteFile(filepath.Clean(name), data, perm)
+}
+
+// Rename renames or moves a file or directory.
+func (OSFS) Rename(oldpath, newpath string) error {
+	return os.Rename(oldpath, newpath)
+}
+
+// Remove removes a file or an empty directory.
+func (OSFS) Remove(name string) error {
+	return os.Remove(name)
+}
+
+// RemoveAll removes a file or a directory and everything it contains.
+func (OSFS) RemoveAll(name string) error {
+	return os.RemoveAll(name)
+}
+
+// Mkdir creates a directory.
+func (OSFS) Mkdir(name string, perm fs.FileMode) error {
+	return os.Mkdir(name, perm)
+}
+
+// MkdirAll creates a directory along with any missing parents.
+func (OSFS) MkdirAll(name string, perm fs.FileMode) error {
+	return os.MkdirAll(name, perm)
+}
+
+// IOFS is a read only backend for an io/fs.FS such as an embed.FS or an
+// archive. Paths are treated as rooted at the root of the io/fs.FS.
+type IOFS struct {
+	fsys fs.FS
+}
+
+// NewIOFS creates a read only backend for an io/fs.FS.
+func NewIOFS(fsys fs.FS) IOFS {
+	return IOFS{fsys: fsys}
+}
+
+// ioFSName converts a rooted path into the unrooted form used by io/fs.
+func ioFSName(name string) string {
+	name = strings.TrimPrefix(path.Clean("/"+filepath.ToSlash(name)), "/")
+	if name == "" {
+		return "."
+	}
+
+	return name
+}
+
+// ReadDir returns the entries of a directory sorted by name.
+func (f IOFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	return fs.ReadDir(f.fsys, ioFSName(name))
+}
+
+// Stat returns information about a file.
+func (f IOFS) Stat(name string) (fs.FileInfo, error) {
+	return fs.Stat(f.fsys, ioFSName(name))
+}
+
+// Open opens a file for reading.
+func (f IOFS) Open(name string) (fs.File, error) {
+	return f.fsys.Open(ioFSName(name))
+}
+
+// ReadFile returns the contents of a file.
+func (f IOFS) ReadFile(name string) ([]byte, error) {
+	return fs.ReadFile(f.fsys, ioFSName(name))
+}
+
+// WriteFile always fails as the backend is read only.
+func (IOFS) WriteFile(name string, _ []byte, _ fs.FileMode) error {
+	return &fs.PathError{Op: "write", Path: name, Err: ErrReadOnly}
+}
+
+// Rename always fails as the backend is read only.
+func (IOFS) Rename(oldpath, _ string) error {
+	return &fs.PathError{Op: "rename", Path: oldpath, Err: ErrReadOnly}
+}
+
+// Remove always fails as the backend is read only.
+func (IOFS) Remove(name string) error {
+	return &fs.PathError{Op: "remove", Path: name, Err: ErrReadOnly}
+}
+
+// RemoveAll always fails as the backend is read only.
+func (IOFS) RemoveAll(name string) error {
+	return &fs.PathError{Op: "remove", Path: name, Err: ErrReadOnly}
+}
+
+// Mkdir always fails as the backend is read only.
+func (IOFS) Mkdir(name string, _ fs.FileMode) error {
+	return &fs.PathError{Op: "mkdir", Path: name, Err: ErrReadOnly}
+}
+
+// MkdirAll always fails as the backend is read only.
+func (IOFS) MkdirAll(name string, _ fs.FileMode) error {
+	return &fs.PathError{Op: "mkdir", Path: name, Err: ErrReadOnly}
+}
+
+// IsOS reports if a backend is the local disk.
+func IsOS(fsys FS) bool {
+	_, ok := fsys.(OSFS)
+
+	return ok
+}
+
+// Lstat returns information about a file without following symbolic links
+// when the backend supports them, otherwise it behaves like Stat.
+func Lstat(fsys FS, name string) (fs.FileInfo, error) {
+	if linkFS, ok := fsys.(LinkFS); ok {
+		return linkFS.Lstat(name)
+	}
+
+	return fsys.Stat(name)
+}
+
+// ReadLink returns the destination of a symbolic link when the backend supports them.
+func ReadLink(fsys FS, name string) (string, error) {
+	if linkFS, ok := fsys.(LinkFS); ok {
+		return linkFS.ReadLink(name)
+	}
+
+	return "", &fs.PathError{Op: "readlink", Path: name, Err: errors.ErrUnsupported}
+}
+
+// maxLinkHops is the number of symbolic links followed before a path is treated as a loop.
+const maxLinkHops = 255
+
+// EvalSymlinks returns the path a symbolic link resolves to. Backends without
+// symbolic links return the path unchanged.
+func EvalSymlinks(fsys FS, name string) (string, error) {
+	if IsOS(fsys) {
+		return filepath.EvalSymlinks(name)
+	}
+
+	if _, ok := fsys.(LinkFS); !ok {
+		return name, nil
+	}
+
+	for range maxLinkHops {
+		fileInfo, err := Lstat(fsys, name)
+		if err != nil {
+			return "", err
+		}
+
+		if fileInfo.Mode()&fs.ModeSymlink == 0 {
+			return name, nil
+		}
+
+		target, err := ReadLink(fsys, name)
+		if err != nil {
+			return "", err
+		}
+
+		if !filepath.IsAbs(target) {
+			target = filepath.Join(filepath.Dir(name), target)
+		}
+
+		name = filepath.Clean(target)
+	}
+
+	return "", &fs.PathError{Op: "evalsymlinks", Path: name, Err: errors.New("too many links")}
+}
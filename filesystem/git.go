@@ -0,0 +1,161 @@
+package filesystem
+
+import (
+	"bytes"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// GitStatus is the state of a file in a git working tree. States are
+// ordered by importance so that directories can summarize their children.
+type GitStatus int
+
+const (
+	GitUnmodified GitStatus = iota
+	GitIgnored
+	GitUntracked
+	GitStaged
+	GitModified
+	GitConflicted
+)
+
+// GitStatusRunner returns the output of `git status --porcelain -z` for a
+// directory, making it possible to swap out the git binary.
+type GitStatusRunner func(dir string) ([]byte, error)
+
+// RunGitStatus runs git status on a directory, including ignored files.
+func RunGitStatus(dir string) ([]byte, error) {
+	cmd := exec.Command("git", "status", "--porcelain", "-z", "--ignored=matching", "--", ".")
+	cmd.Dir = dir
+
+	return cmd.Output()
+}
+
+// GitStatuses holds the state of every file in a git working tree
+// reported by git status.
+type GitStatuses struct {
+	root        string
+	files       map[string]GitStatus
+	directories map[string]GitStatus
+}
+
+// FindGitRoot returns the root of the git working tree containing a directory.
+func FindGitRoot(dir string) (string, bool) {
+	dir = filepath.Clean(dir)
+
+	for {
+		if _, err := os.Stat(filepath.Join(dir, ".git")); err == nil {
+			return dir, true
+		}
+
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", false
+		}
+
+		dir = parent
+	}
+}
+
+// GetGitStatus returns the state of the files in the git working tree
+// containing a directory, or nil when the directory is not in a working tree.
+func GetGitStatus(dir string, run GitStatusRunner) (*GitStatuses, error) {
+	root, ok := FindGitRoot(dir)
+	if !ok {
+		return nil, nil
+	}
+
+	output, err := run(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	return ParseGitStatus(root, output), nil
+}
+
+// ParseGitStatus parses the output of `git status --porcelain -z` for the
+// working tree at root.
+func ParseGitStatus(root string, output []byte) *GitStatuses {
+	statuses := &GitStatuses{
+		root:        root,
+		files:       make(map[string]GitStatus),
+		directories: make(map[string]GitStatus),
+	}
+
+	entries := bytes.Split(output, []byte{0})
+	for i := 0; i < len(entries); i++ {
+		entry := string(entries[i])
+		if len(entry) < 4 {
+			continue
+		}
+
+		code, name := entry[:2], strings.TrimSuffix(entry[3:], "/")
+
+		// Renames and copies are followed by the original path.
+		if code[0] == 'R' || code[0] == 'C' {
+			i++
+		}
+
+		status := parseGitStatusCode(code)
+		statuses.files[name] = status
+
+		// Ignored files do not make the directories containing them look ignored.
+		if status == GitIgnored {
+			continue
+		}
+
+		for dir := path.Dir(name); dir != "."; dir = path.Dir(dir) {
+			statuses.directories[dir] = max(statuses.directories[dir], status)
+		}
+	}
+
+	return statuses
+}
+
+// parseGitStatusCode returns the state for the two letter code of git status.
+func parseGitStatusCode(code string) GitStatus {
+	switch code {
+	case "??":
+		return GitUntracked
+	case "!!":
+		return GitIgnored
+	case "DD", "AU", "UD", "UA", "DU", "AA", "UU":
+		return GitConflicted
+	}
+
+	if code[1] != ' ' {
+		return GitModified
+	}
+
+	return GitStaged
+}
+
+// Root returns the root of the working tree.
+func (s *GitStatuses) Root() string {
+	return s.root
+}
+
+// Status returns the state of a file, or the most important state of the
+// files in a directory.
+func (s *GitStatuses) Status(name string) GitStatus {
+	relPath, err := filepath.Rel(s.root, name)
+	if err != nil || relPath == ".." || strings.HasPrefix(relPath, ".."+string(filepath.Separator)) {
+		return GitUnmodified
+	}
+
+	relPath = filepath.ToSlash(relPath)
+
+	status := max(s.files[relPath], s.directories[relPath])
+
+	// Untracked and ignored directories are reported without their contents.
+	for dir := relPath; dir != "."; dir = path.Dir(dir) {
+		if parentStatus := s.files[dir]; parentStatus == GitUntracked || parentStatus == GitIgnored {
+			return max(status, parentStatus)
+		}
+	}
+
+	return status
+}
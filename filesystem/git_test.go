@@ -0,0 +1,232 @@
+package filesystem
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseGitStatus(t *testing.T) {
+	root := filepath.FromSlash("/repo")
+
+	output := strings.Join([]string{
+		"R  docs/new.md",
+		"docs/old.md",
+		"C  copy.go",
+		"original.go",
+		"UU conflict.go",
+		"AA both/added.go",
+		"DU deleted/by/us.go",
+		"A  src/added.go",
+		" M src/deep/modified.go",
+		"MM src/both.go",
+		"?? notes/",
+		"!! build/",
+		"!! src/cache.tmp",
+		"!! logs/debug.log",
+		"",
+	}, "\x00")
+
+	statuses := ParseGitStatus(root, []byte(output))
+
+	tests := []struct {
+		name string
+		want GitStatus
+	}{
+		{"docs/new.md", GitStaged},
+		{"docs/old.md", GitUnmodified},
+		{"docs", GitStaged},
+		{"copy.go", GitStaged},
+		{"original.go", GitUnmodified},
+		{"conflict.go", GitConflicted},
+		{"both/added.go", GitConflicted},
+		{"both", GitConflicted},
+		{"deleted/by/us.go", GitConflicted},
+		{"deleted", GitConflicted},
+		{"src/added.go", GitStaged},
+		{"src/deep/modified.go", GitModified},
+		{"src/deep", GitModified},
+		{"src/both.go", GitModified},
+		{"src", GitModified},
+		{"src/cache.tmp", GitIgnored},
+		{"notes", GitUntracked},
+		{"notes/todo.md", GitUntracked},
+		{"build", GitIgnored},
+		{"build/out/binary", GitIgnored},
+		{"logs/debug.log", GitIgnored},
+		{"logs", GitUnmodified},
+		{"unchanged.go", GitUnmodified},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := statuses.Status(filepath.Join(root, filepath.FromSlash(tt.name))); got != tt.want {
+				t.Errorf("Status(%q) = %v, want %v", tt.name, got, tt.want)
+			}
+		})
+	}
+
+	if got := statuses.Status(filepath.FromSlash("/elsewhere/conflict.go")); got != GitUnmodified {
+		t.Errorf("Status outside of the working tree = %v, want %v", got, GitUnmodified)
+	}
+}
+
+func TestParseGitStatusEmpty(t *testing.T) {
+	statuses := ParseGitStatus("/repo", nil)
+
+	if got := statuses.Status("/repo/main.go"); got != GitUnmodified {
+		t.Errorf("Status = %v, want %v", got, GitUnmodified)
+	}
+}
+
+// runGit runs git in a directory, isolated from the configuration of the user.
+func runGit(t *testing.T, dir string, args ...string) {
+	t.Helper()
+
+	args = append([]string{"-c", "user.name=test", "-c", "user.email=test@example.com", "-c", "commit.gpgsign=false"}, args...)
+
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+
+	if output, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("git %s: %v\n%s", strings.Join(args, " "), err, output)
+	}
+}
+
+func TestRunGitStatus(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("GIT_CONFIG_NOSYSTEM", "1")
+
+	dir := t.TempDir()
+
+	writeFile := func(name, content string) {
+		t.Helper()
+
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	runGit(t, dir, "init", "--quiet")
+
+	writeFile(".gitignore", "*.log\n")
+	writeFile("tracked.txt", "one\n")
+	writeFile("src/renamed.go", "package src\n")
+	runGit(t, dir, "add", ".")
+	runGit(t, dir, "commit", "--quiet", "-m", "initial")
+
+	writeFile("tracked.txt", "two\n")
+	writeFile("src/staged.go", "package src\n")
+	runGit(t, dir, "add", "src/staged.go")
+	runGit(t, dir, "mv", "src/renamed.go", "src/moved.go")
+	writeFile("untracked/file.txt", "new\n")
+	writeFile("debug.log", "ignored\n")
+	writeFile("logs/app.log", "ignored\n")
+
+	statuses, err := GetGitStatus(dir, RunGitStatus)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if statuses == nil {
+		t.Fatal("GetGitStatus returned no statuses for a working tree")
+	}
+
+	if statuses.Root() != dir {
+		t.Errorf("Root() = %q, want %q", statuses.Root(), dir)
+	}
+
+	tests := []struct {
+		name string
+		want GitStatus
+	}{
+		{"tracked.txt", GitModified},
+		{"src/staged.go", GitStaged},
+		{"src/moved.go", GitStaged},
+		{"src/renamed.go", GitUnmodified},
+		{"src", GitStaged},
+		{"untracked", GitUntracked},
+		{"untracked/file.txt", GitUntracked},
+		{"debug.log", GitIgnored},
+		{"logs/app.log", GitIgnored},
+		{".gitignore", GitUnmodified},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := statuses.Status(filepath.Join(dir, filepath.FromSlash(tt.name))); got != tt.want {
+				t.Errorf("Status(%q) = %v, want %v", tt.name, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRunGitStatusSubdirectory(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("GIT_CONFIG_NOSYSTEM", "1")
+
+	dir := t.TempDir()
+	runGit(t, dir, "init", "--quiet")
+
+	for _, name := range []string{"outside.txt", filepath.Join("sub", "inside.txt")} {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := os.WriteFile(path, nil, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Only the directory git status is run in is reported, with paths
+	// relative to the root of the working tree.
+	statuses, err := GetGitStatus(filepath.Join(dir, "sub"), RunGitStatus)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if statuses.Root() != dir {
+		t.Errorf("Root() = %q, want %q", statuses.Root(), dir)
+	}
+
+	if got := statuses.Status(filepath.Join(dir, "sub", "inside.txt")); got != GitUntracked {
+		t.Errorf("Status(inside.txt) = %v, want %v", got, GitUntracked)
+	}
+
+	if got := statuses.Status(filepath.Join(dir, "outside.txt")); got != GitUnmodified {
+		t.Errorf("Status(outside.txt) = %v, want %v", got, GitUnmodified)
+	}
+}
+
+func TestGetGitStatusOutsideWorkingTree(t *testing.T) {
+	dir := t.TempDir()
+
+	if _, ok := FindGitRoot(dir); ok {
+		t.Skip("the temporary directory is within a git working tree")
+	}
+
+	statuses, err := GetGitStatus(dir, func(string) ([]byte, error) {
+		t.Fatal("git status was run outside of a working tree")
+
+		return nil, nil
+	})
+	if err != nil || statuses != nil {
+		t.Errorf("GetGitStatus() = %v, %v, want nil, nil", statuses, err)
+	}
+}
@@ -0,0 +1,421 @@
+package filesystem
+
+import (
+	"bufio"
+	"bytes"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// IgnoreFiles are the files read in each directory for ignore patterns.
+// Patterns in later files take precedence over earlier ones.
+var IgnoreFiles = []string{".gitignore", ".ignore"}
+
+// ignorePattern is a single line of an ignore file.
+type ignorePattern struct {
+	base    string
+	regexp  *regexp.Regexp
+	negate  bool
+	dirOnly bool
+}
+
+// Ignorer decides which files are ignored using gitignore semantics. Ignore
+// files are read on demand from every directory between the root of the git
+// working tree and the file, and patterns in deeper directories take precedence
+// over those above them. Within a working tree the patterns of
+// $GIT_DIR/info/exclude and of the file set by core.excludesFile apply as well,
+// with a lower precedence than any ignore file.
+type Ignorer struct {
+	fsys FS
+	root string
+	// base is the directory patterns are relative to, which is the root of the
+	// git working tree containing root, or root when it is not in one.
+	base string
+	// prefix is the path of root relative to base, empty when they are the same.
+	prefix   string
+	patterns []ignorePattern
+
+	mu          sync.Mutex
+	directories map[string][]ignorePattern
+	ignored     map[string]bool
+}
+
+// NewIgnorer returns an ignorer for the files below root. The extra patterns
+// apply to the whole of root with a lower precedence than any ignore file.
+func NewIgnorer(fsys FS, root string, patterns ...string) *Ignorer {
+	i := &Ignorer{
+		fsys:        fsys,
+		root:        filepath.Clean(root),
+		base:        filepath.Clean(root),
+		directories: make(map[string][]ignorePattern),
+		ignored:     make(map[string]bool),
+	}
+
+	var excludes []ignorePattern
+
+	if workTree, gitDir, ok := findGitDirectory(fsys, i.root); ok {
+		i.base = workTree
+
+		if prefix, err := filepath.Rel(workTree, i.root); err == nil && prefix != "." {
+			i.prefix = filepath.ToSlash(prefix)
+		}
+
+		if data, err := readExcludesFile(fsys, gitDir); err == nil {
+			excludes = append(excludes, parseIgnorePatterns("", data)...)
+		}
+
+		if data, err := fsys.ReadFile(filepath.Join(gitDir, "info", "exclude")); err == nil {
+			excludes = append(excludes, parseIgnorePatterns("", data)...)
+		}
+	}
+
+	i.patterns = append(parseIgnorePatterns(i.prefix, []byte(strings.Join(patterns, "\n"))), excludes...)
+
+	return i
+}
+
+// findGitDirectory returns the root of the git working tree containing a directory
+// along with its git directory, which holds the files shared by every worktree.
+func findGitDirectory(fsys FS, dir string) (string, string, bool) {
+	for {
+		gitDir := filepath.Join(dir, ".git")
+
+		info, err := fsys.Stat(gitDir)
+		if err == nil && info.IsDir() {
+			return dir, gitDir, true
+		}
+
+		// Worktrees and submodules have a file pointing to their git directory.
+		if err == nil {
+			data, err := fsys.ReadFile(gitDir)
+			if target, ok := strings.CutPrefix(strings.TrimSpace(string(data)), "gitdir:"); err == nil && ok {
+				gitDir = resolveGitPath(dir, strings.TrimSpace(target))
+
+				if common, err := fsys.ReadFile(filepath.Join(gitDir, "commondir")); err == nil {
+					gitDir = resolveGitPath(gitDir, strings.TrimSpace(string(common)))
+				}
+
+				return dir, gitDir, true
+			}
+		}
+
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", "", false
+		}
+
+		dir = parent
+	}
+}
+
+// resolveGitPath resolves a path found in a git file against the directory containing it.
+func resolveGitPath(dir, name string) string {
+	name = filepath.FromSlash(name)
+	if filepath.IsAbs(name) {
+		return filepath.Clean(name)
+	}
+
+	return filepath.Join(dir, name)
+}
+
+// readExcludesFile reads the file set by core.excludesFile, which defaults to
+// $XDG_CONFIG_HOME/git/ignore. It is only read for working trees on the local
+// disk, as it belongs to the user rather than to the working tree.
+func readExcludesFile(fsys FS, gitDir string) ([]byte, error) {
+	if !IsOS(fsys) {
+		return nil, os.ErrNotExist
+	}
+
+	home, _ := os.UserHomeDir()
+
+	configHome := os.Getenv("XDG_CONFIG_HOME")
+	if configHome == "" {
+		configHome = filepath.Join(home, ".config")
+	}
+
+	excludesFile := filepath.Join(configHome, "git", "ignore")
+
+	// Later configuration files take precedence over earlier ones.
+	for _, config := range []string{
+		filepath.Join(configHome, "git", "config"),
+		filepath.Join(home, ".gitconfig"),
+		filepath.Join(gitDir, "config"),
+	} {
+		data, err := os.ReadFile(config)
+		if err != nil {
+			continue
+		}
+
+		if value, ok := gitConfigValue(data, "core", "excludesfile"); ok {
+			excludesFile = value
+		}
+	}
+
+	if rest, ok := strings.CutPrefix(excludesFile, "~/"); ok {
+		excludesFile = filepath.Join(home, rest)
+	}
+
+	return os.ReadFile(excludesFile)
+}
+
+// gitConfigValue returns the last value of a key in a section of a git
+// configuration file. Section and key names are compared ignoring case.
+func gitConfigValue(data []byte, section, key string) (string, bool) {
+	var current, value string
+	var found bool
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+
+		if name, ok := strings.CutPrefix(line, "["); ok {
+			name, _, _ = strings.Cut(name, "]")
+			current = strings.ToLower(strings.TrimSpace(name))
+
+			continue
+		}
+
+		if current != section || line == "" || line[0] == '#' || line[0] == ';' {
+			continue
+		}
+
+		name, rest, _ := strings.Cut(line, "=")
+		if !strings.EqualFold(strings.TrimSpace(name), key) {
+			continue
+		}
+
+		rest = strings.TrimSpace(rest)
+		if unquoted, ok := strings.CutPrefix(rest, "\""); ok {
+			rest, _, _ = strings.Cut(unquoted, "\"")
+		} else if comment := strings.IndexAny(rest, "#;"); comment >= 0 {
+			rest = strings.TrimSpace(rest[:comment])
+		}
+
+		value, found = rest, true
+	}
+
+	return value, found
+}
+
+// IsIgnored reports if a file or directory is ignored, either by a pattern
+// or because one of the directories containing it is ignored. Only the
+// directories below root are taken into account, so that the files of an
+// ignored directory are not all hidden once it is opened.
+func (i *Ignorer) IsIgnored(name string, isDir bool) bool {
+	relPath, err := filepath.Rel(i.root, name)
+	if err != nil || relPath == "." || relPath == ".." || strings.HasPrefix(relPath, ".."+string(filepath.Separator)) {
+		return false
+	}
+
+	relPath = path.Join(i.prefix, filepath.ToSlash(relPath))
+
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	// A file can not be re-included if a directory containing it is ignored.
+	if dir := path.Dir(relPath); dir != "." && dir != i.prefix && i.isDirectoryIgnored(dir) {
+		return true
+	}
+
+	return i.matches(relPath, isDir)
+}
+
+// isDirectoryIgnored reports if a directory or any directory containing it is ignored.
+func (i *Ignorer) isDirectoryIgnored(dir string) bool {
+	if ignored, ok := i.ignored[dir]; ok {
+		return ignored
+	}
+
+	ignored := false
+	if parent := path.Dir(dir); parent != "." && parent != i.prefix {
+		ignored = i.isDirectoryIgnored(parent)
+	}
+
+	ignored = ignored || i.matches(dir, true)
+	i.ignored[dir] = ignored
+
+	return ignored
+}
+
+// matches reports if the last pattern matching a path ignores it.
+func (i *Ignorer) matches(relPath string, isDir bool) bool {
+	ignored := matchIgnorePatterns(i.patterns, relPath, isDir, false)
+
+	var dirs []string
+	for dir := path.Dir(relPath); dir != "."; dir = path.Dir(dir) {
+		dirs = append(dirs, dir)
+	}
+
+	dirs = append(dirs, "")
+
+	// Walk down from the root so that deeper ignore files take precedence.
+	for d := len(dirs) - 1; d >= 0; d-- {
+		ignored = matchIgnorePatterns(i.directoryPatterns(dirs[d]), relPath, isDir, ignored)
+	}
+
+	return ignored
+}
+
+// directoryPatterns returns the patterns of the ignore files in a directory.
+func (i *Ignorer) directoryPatterns(dir string) []ignorePattern {
+	if patterns, ok := i.directories[dir]; ok {
+		return patterns
+	}
+
+	var patterns []ignorePattern
+
+	for _, name := range IgnoreFiles {
+		data, err := i.fsys.ReadFile(filepath.Join(i.base, filepath.FromSlash(dir), name))
+		if err != nil {
+			continue
+		}
+
+		patterns = append(patterns, parseIgnorePatterns(dir, data)...)
+	}
+
+	i.directories[dir] = patterns
+
+	return patterns
+}
+
+// matchIgnorePatterns applies patterns in order to a path, starting from
+// whether it is already ignored.
+func matchIgnorePatterns(patterns []ignorePattern, relPath string, isDir, ignored bool) bool {
+	for _, pattern := range patterns {
+		name := relPath
+		if pattern.base != "" {
+			if !strings.HasPrefix(relPath, pattern.base+"/") {
+				continue
+			}
+
+			name = strings.TrimPrefix(relPath, pattern.base+"/")
+		}
+
+		if pattern.dirOnly && !isDir {
+			continue
+		}
+
+		if pattern.regexp.MatchString(name) {
+			ignored = !pattern.negate
+		}
+	}
+
+	return ignored
+}
+
+// parseIgnorePatterns parses the lines of an ignore file found in base.
+func parseIgnorePatterns(base string, data []byte) []ignorePattern {
+	var patterns []ignorePattern
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		if pattern, ok := parseIgnorePattern(base, scanner.Text()); ok {
+			patterns = append(patterns, pattern)
+		}
+	}
+
+	return patterns
+}
+
+// parseIgnorePattern parses a single line of an ignore file.
+func parseIgnorePattern(base, line string) (ignorePattern, bool) {
+	line = strings.TrimSuffix(line, "\r")
+
+	// Trailing spaces are ignored unless they are escaped.
+	for strings.HasSuffix(line, " ") && !strings.HasSuffix(line, "\\ ") {
+		line = strings.TrimSuffix(line, " ")
+	}
+
+	if line == "" || strings.HasPrefix(line, "#") {
+		return ignorePattern{}, false
+	}
+
+	pattern := ignorePattern{base: base}
+
+	if strings.HasPrefix(line, "!") {
+		pattern.negate = true
+		line = line[1:]
+	}
+
+	if strings.HasSuffix(line, "/") {
+		pattern.dirOnly = true
+		line = strings.TrimSuffix(line, "/")
+	}
+
+	// Patterns without a slash match at any depth, others
+	// are relative to the directory of the ignore file.
+	if strings.Contains(line, "/") {
+		line = strings.TrimPrefix(line, "/")
+	} else {
+		line = "**/" + line
+	}
+
+	if line == "" || line == "**/" {
+		return ignorePattern{}, false
+	}
+
+	expression, err := regexp.Compile(ignoreGlobToRegexp(line))
+	if err != nil {
+		return ignorePattern{}, false
+	}
+
+	pattern.regexp = expression
+
+	return pattern, true
+}
+
+// ignoreGlobToRegexp converts a gitignore glob into a regular expression.
+func ignoreGlobToRegexp(glob string) string {
+	var expression strings.Builder
+
+	expression.WriteString("^")
+
+	for i := 0; i < len(glob); i++ {
+		switch c := glob[i]; {
+		case strings.HasPrefix(glob[i:], "**/") && (i == 0 || glob[i-1] == '/'):
+			// Leading or middle "**/" matches zero or more directories.
+			expression.WriteString("(?:.*/)?")
+			i += 2
+		case glob[i:] == "**" && i > 0 && glob[i-1] == '/':
+			// Trailing "/**" matches everything inside a directory.
+			expression.WriteString(".+")
+			i++
+		case c == '*':
+			for i+1 < len(glob) && glob[i+1] == '*' {
+				i++
+			}
+
+			expression.WriteString("[^/]*")
+		case c == '?':
+			expression.WriteString("[^/]")
+		case c == '[':
+			end := strings.IndexByte(glob[i+1:], ']')
+			if end < 0 {
+				expression.WriteString(regexp.QuoteMeta("["))
+
+				continue
+			}
+
+			class := glob[i+1 : i+1+end]
+			if strings.HasPrefix(class, "!") || strings.HasPrefix(class, "^") {
+				class = "^" + class[1:]
+			}
+
+			expression.WriteString("[" + strings.ReplaceAll(class, "\\", "\\\\") + "]")
+			i += end + 1
+		case c == '\\' && i+1 < len(glob):
+			i++
+			expression.WriteString(regexp.QuoteMeta(string(glob[i])))
+		default:
+			expression.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+
+	expression.WriteString("$")
+
+	return expression.String()
+}
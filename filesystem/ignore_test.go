@@ -0,0 +1,490 @@
+package filesystem
+
+import (
+	"os"
+	"path"
+	"path/filepath"
+	"testing"
+)
+
+func TestIgnorer(t *testing.T) {
+	tests := []struct {
+		name   string
+		files  map[string]string
+		path   string
+		isDir  bool
+		want   bool
+		extras []string
+	}{
+		{
+			name:  "unanchored pattern matches at any depth",
+			files: map[string]string{".gitignore": "*.log\n"},
+			path:  "a/b/debug.log",
+			want:  true,
+		},
+		{
+			name:  "unanchored pattern matches at the root",
+			files: map[string]string{".gitignore": "*.log\n"},
+			path:  "debug.log",
+			want:  true,
+		},
+		{
+			name:  "wildcard does not match a slash",
+			files: map[string]string{".gitignore": "a*b\n"},
+			path:  "a/b",
+			want:  false,
+		},
+		{
+			name:  "anchored pattern matches at the root",
+			files: map[string]string{".gitignore": "/build\n"},
+			path:  "build",
+			isDir: true,
+			want:  true,
+		},
+		{
+			name:  "anchored pattern does not match deeper",
+			files: map[string]string{".gitignore": "/build\n"},
+			path:  "src/build",
+			isDir: true,
+			want:  false,
+		},
+		{
+			name:  "pattern with a middle slash is anchored",
+			files: map[string]string{".gitignore": "docs/*.html\n"},
+			path:  "src/docs/index.html",
+			want:  false,
+		},
+		{
+			name:  "pattern with a middle slash matches from the root",
+			files: map[string]string{".gitignore": "docs/*.html\n"},
+			path:  "docs/index.html",
+			want:  true,
+		},
+		{
+			name:  "directory only pattern matches a directory",
+			files: map[string]string{".gitignore": "cache/\n"},
+			path:  "a/cache",
+			isDir: true,
+			want:  true,
+		},
+		{
+			name:  "directory only pattern does not match a file",
+			files: map[string]string{".gitignore": "cache/\n"},
+			path:  "a/cache",
+			want:  false,
+		},
+		{
+			name:  "files in an ignored directory are ignored",
+			files: map[string]string{".gitignore": "cache/\n"},
+			path:  "a/cache/entry",
+			want:  true,
+		},
+		{
+			name:  "leading double star matches at any depth",
+			files: map[string]string{".gitignore": "**/vendor/lib\n"},
+			path:  "a/b/vendor/lib",
+			want:  true,
+		},
+		{
+			name:  "leading double star matches at the root",
+			files: map[string]string{".gitignore": "**/vendor/lib\n"},
+			path:  "vendor/lib",
+			want:  true,
+		},
+		{
+			name:  "middle double star matches no directories",
+			files: map[string]string{".gitignore": "a/**/b\n"},
+			path:  "a/b",
+			want:  true,
+		},
+		{
+			name:  "middle double star matches several directories",
+			files: map[string]string{".gitignore": "a/**/b\n"},
+			path:  "a/x/y/b",
+			want:  true,
+		},
+		{
+			name:  "trailing double star matches inside a directory",
+			files: map[string]string{".gitignore": "out/**\n"},
+			path:  "out/x/y.txt",
+			want:  true,
+		},
+		{
+			name:  "trailing double star does not match the directory",
+			files: map[string]string{".gitignore": "out/**\n"},
+			path:  "out",
+			isDir: true,
+			want:  false,
+		},
+		{
+			name:  "negation re-includes a file",
+			files: map[string]string{".gitignore": "*.log\n!keep.log\n"},
+			path:  "keep.log",
+			want:  false,
+		},
+		{
+			name:  "negation only applies to what it matches",
+			files: map[string]string{".gitignore": "*.log\n!keep.log\n"},
+			path:  "other.log",
+			want:  true,
+		},
+		{
+			name:  "later pattern takes precedence over negation",
+			files: map[string]string{".gitignore": "!keep.log\n*.log\n"},
+			path:  "keep.log",
+			want:  true,
+		},
+		{
+			name:  "negation can not re-include a file in an ignored directory",
+			files: map[string]string{".gitignore": "logs/\n!logs/keep.log\n"},
+			path:  "logs/keep.log",
+			want:  true,
+		},
+		{
+			name:  "negation re-includes a file when only the contents are ignored",
+			files: map[string]string{".gitignore": "logs/*\n!logs/keep.log\n"},
+			path:  "logs/keep.log",
+			want:  false,
+		},
+		{
+			name:  "escaped negation matches a literal exclamation mark",
+			files: map[string]string{".gitignore": "\\!important\n"},
+			path:  "!important",
+			want:  true,
+		},
+		{
+			name:  "comments and blank lines are skipped",
+			files: map[string]string{".gitignore": "# *.go\n\n"},
+			path:  "main.go",
+			want:  false,
+		},
+		{
+			name:  "trailing spaces are trimmed",
+			files: map[string]string{".gitignore": "*.tmp   \n"},
+			path:  "a.tmp",
+			want:  true,
+		},
+		{
+			name:  "character classes match",
+			files: map[string]string{".gitignore": "file[0-9].txt\n"},
+			path:  "file3.txt",
+			want:  true,
+		},
+		{
+			name:  "negated character classes match",
+			files: map[string]string{".gitignore": "file[!0-9].txt\n"},
+			path:  "file3.txt",
+			want:  false,
+		},
+		{
+			name:  "nested ignore file applies below its directory",
+			files: map[string]string{"src/.gitignore": "*.gen.go\n"},
+			path:  "src/api/types.gen.go",
+			want:  true,
+		},
+		{
+			name:  "nested ignore file does not apply outside its directory",
+			files: map[string]string{"src/.gitignore": "*.gen.go\n"},
+			path:  "types.gen.go",
+			want:  false,
+		},
+		{
+			name:  "nested ignore file anchors patterns to its directory",
+			files: map[string]string{"src/.gitignore": "/tmp\n"},
+			path:  "src/tmp",
+			isDir: true,
+			want:  true,
+		},
+		{
+			name:  "nested anchored pattern does not match deeper",
+			files: map[string]string{"src/.gitignore": "/tmp\n"},
+			path:  "src/a/tmp",
+			isDir: true,
+			want:  false,
+		},
+		{
+			name: "nested ignore file re-includes what a parent ignores",
+			files: map[string]string{
+				".gitignore":     "*.log\n",
+				"src/.gitignore": "!*.log\n",
+			},
+			path: "src/app.log",
+			want: false,
+		},
+		{
+			name: "nested ignore file ignores what a parent re-includes",
+			files: map[string]string{
+				".gitignore":     "*.log\n!app.log\n",
+				"src/.gitignore": "app.log\n",
+			},
+			path: "src/app.log",
+			want: true,
+		},
+		{
+			name:  "ignore file takes precedence over gitignore",
+			files: map[string]string{".gitignore": "*.log\n", ".ignore": "!*.log\n"},
+			path:  "app.log",
+			want:  false,
+		},
+		{
+			name:   "extra patterns apply to the whole tree",
+			path:   "a/node_modules",
+			isDir:  true,
+			want:   true,
+			extras: []string{"node_modules/"},
+		},
+		{
+			name:   "ignore files take precedence over extra patterns",
+			files:  map[string]string{".gitignore": "!node_modules/\n"},
+			path:   "node_modules",
+			isDir:  true,
+			want:   false,
+			extras: []string{"node_modules/"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fsys := NewMemFS()
+
+			for name, content := range tt.files {
+				name = path.Join("/root", name)
+				if err := fsys.MkdirAll(path.Dir(name), 0o755); err != nil {
+					t.Fatal(err)
+				}
+
+				if err := fsys.WriteFile(name, []byte(content), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			ignorer := NewIgnorer(fsys, "/root", tt.extras...)
+			if got := ignorer.IsIgnored(path.Join("/root", tt.path), tt.isDir); got != tt.want {
+				t.Errorf("IsIgnored(%q, %v) = %v, want %v", tt.path, tt.isDir, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIgnorerGitWorkTree(t *testing.T) {
+	tests := []struct {
+		name   string
+		files  map[string]string
+		root   string
+		path   string
+		isDir  bool
+		want   bool
+		extras []string
+	}{
+		{
+			name:  "info exclude ignores files",
+			files: map[string]string{"/repo/.git/info/exclude": "*.secret\n"},
+			root:  "/repo",
+			path:  "/repo/sub/key.secret",
+			want:  true,
+		},
+		{
+			name: "ignore files take precedence over info exclude",
+			files: map[string]string{
+				"/repo/.git/info/exclude": "*.log\n",
+				"/repo/.gitignore":        "!keep.log\n",
+			},
+			root: "/repo",
+			path: "/repo/keep.log",
+			want: false,
+		},
+		{
+			name: "info exclude takes precedence over extra patterns",
+			files: map[string]string{
+				"/repo/.git/info/exclude": "!keep.log\n",
+			},
+			root:   "/repo",
+			path:   "/repo/keep.log",
+			want:   false,
+			extras: []string{"*.log"},
+		},
+		{
+			name: "ignore files above the root apply",
+			files: map[string]string{
+				"/repo/.git/HEAD":  "",
+				"/repo/.gitignore": "*.log\n",
+			},
+			root: "/repo/sub",
+			path: "/repo/sub/app.log",
+			want: true,
+		},
+		{
+			name: "patterns above the root are anchored to their directory",
+			files: map[string]string{
+				"/repo/.git/HEAD":  "",
+				"/repo/.gitignore": "/sub/build\n",
+			},
+			root:  "/repo/sub",
+			path:  "/repo/sub/build",
+			isDir: true,
+			want:  true,
+		},
+		{
+			name: "extra patterns are anchored to the root",
+			files: map[string]string{
+				"/repo/.git/HEAD": "",
+			},
+			root:   "/repo/sub",
+			path:   "/repo/sub/build",
+			isDir:  true,
+			want:   true,
+			extras: []string{"/build/"},
+		},
+		{
+			name: "files of an ignored root are shown",
+			files: map[string]string{
+				"/repo/.git/HEAD":  "",
+				"/repo/.gitignore": "vendor/\n",
+			},
+			root: "/repo/vendor",
+			path: "/repo/vendor/lib.go",
+			want: false,
+		},
+		{
+			name: "git file pointing to the git directory",
+			files: map[string]string{
+				"/repo/.git":                   "gitdir: /store/repo.git\n",
+				"/store/repo.git/info/exclude": "*.tmp\n",
+			},
+			root: "/repo",
+			path: "/repo/a.tmp",
+			want: true,
+		},
+		{
+			name: "worktree uses the common git directory",
+			files: map[string]string{
+				"/repo/.git":                    "gitdir: ../store/worktrees/wt\n",
+				"/store/worktrees/wt/commondir": "../..\n",
+				"/store/info/exclude":           "*.tmp\n",
+			},
+			root: "/repo",
+			path: "/repo/a.tmp",
+			want: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fsys := NewMemFS()
+
+			for name, content := range tt.files {
+				writeMemFile(t, fsys, name, content)
+			}
+
+			if err := fsys.MkdirAll(tt.root, 0o755); err != nil {
+				t.Fatal(err)
+			}
+
+			ignorer := NewIgnorer(fsys, tt.root, tt.extras...)
+			if got := ignorer.IsIgnored(tt.path, tt.isDir); got != tt.want {
+				t.Errorf("IsIgnored(%q, %v) = %v, want %v", tt.path, tt.isDir, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIgnorerExcludesFile(t *testing.T) {
+	tests := []struct {
+		name    string
+		files   map[string]string
+		ignored string
+		shown   string
+	}{
+		{
+			name:    "default excludes file",
+			files:   map[string]string{"config/git/ignore": "*.a\n"},
+			ignored: "file.a",
+			shown:   "file.b",
+		},
+		{
+			name: "excludes file set in the global configuration",
+			files: map[string]string{
+				"config/git/ignore": "*.a\n",
+				".gitconfig":        "[user]\n\tname = test\n[Core]\n\texcludesFile = ~/global-ignore ; comment\n",
+				"global-ignore":     "*.b\n",
+			},
+			ignored: "file.b",
+			shown:   "file.a",
+		},
+		{
+			name: "repository configuration takes precedence",
+			files: map[string]string{
+				".gitconfig":        "[core]\n\texcludesfile = ~/global-ignore\n",
+				"global-ignore":     "*.b\n",
+				"repo/.git/config":  "[core]\n\texcludesFile = \"~/repo ignore\"\n",
+				"repo ignore":       "*.c\n",
+				"config/git/ignore": "*.a\n",
+			},
+			ignored: "file.c",
+			shown:   "file.b",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			home := t.TempDir()
+			t.Setenv("HOME", home)
+			t.Setenv("XDG_CONFIG_HOME", filepath.Join(home, "config"))
+
+			if err := os.MkdirAll(filepath.Join(home, "repo", ".git"), 0o755); err != nil {
+				t.Fatal(err)
+			}
+
+			for name, content := range tt.files {
+				writeTestFile(t, filepath.Join(home, filepath.FromSlash(name)), content)
+			}
+
+			ignorer := NewIgnorer(OSFS{}, filepath.Join(home, "repo"))
+
+			if !ignorer.IsIgnored(filepath.Join(home, "repo", tt.ignored), false) {
+				t.Errorf("IsIgnored(%q) = false, want true", tt.ignored)
+			}
+
+			if ignorer.IsIgnored(filepath.Join(home, "repo", tt.shown), false) {
+				t.Errorf("IsIgnored(%q) = true, want false", tt.shown)
+			}
+		})
+	}
+}
+
+func TestIgnorerOutsideRoot(t *testing.T) {
+	ignorer := NewIgnorer(NewMemFS(), "/root", "*")
+
+	for _, name := range []string{"/root", "/other/file", "/"} {
+		if ignorer.IsIgnored(name, false) {
+			t.Errorf("IsIgnored(%q) = true, want false", name)
+		}
+	}
+}
+
+func TestWithIgnorer(t *testing.T) {
+	fsys := NewMemFS()
+	writeMemFile(t, fsys, "/root/.gitignore", "build/\n")
+	writeMemFile(t, fsys, "/root/main.go", "package main")
+	writeMemFile(t, fsys, "/root/build/main.go", "compiled")
+
+	size, err := GetDirectoryItemSizeFS(fsys, "/root")
+	if err != nil || size != 27 {
+		t.Errorf("GetDirectoryItemSizeFS() = %d, %v, want 27", size, err)
+	}
+
+	size, err = GetDirectoryItemSizeWithIgnorerFS(fsys, "/root", NewIgnorer(fsys, "/root"))
+	if err != nil || size != 19 {
+		t.Errorf("GetDirectoryItemSizeWithIgnorerFS() = %d, %v, want 19", size, err)
+	}
+
+	paths, _, err := FindFilesByNameFS(fsys, "main", "/root")
+	if err != nil || len(paths) != 2 {
+		t.Errorf("FindFilesByNameFS() = %v, %v, want both files", paths, err)
+	}
+
+	paths, _, err = FindFilesByNameWithIgnorerFS(fsys, "main", "/root", NewIgnorer(fsys, "/root"))
+	if err != nil || len(paths) != 1 || paths[0] != "/root/main.go" {
+		t.Errorf("FindFilesByNameWithIgnorerFS() = %v, %v, want only /root/main.go", paths, err)
+	}
+}
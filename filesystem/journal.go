@@ -0,0 +1,406 @@
+package filesystem
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"slices"
+	"sync"
+	"time"
+)
+
+// OperationKind represents the kind of a file operation recorded in a journal.
+type OperationKind int
+
+// Operations which can be undone.
+const (
+	CreateOperation OperationKind = iota
+	RenameOperation
+	CopyOperation
+	TrashOperation
+	ZipOperation
+	UnzipOperation
+	ExtractOperation
+)
+
+// String returns the name of the operation.
+func (k OperationKind) String() string {
+	switch k {
+	case CreateOperation:
+		return "create"
+	case RenameOperation:
+		return "rename"
+	case CopyOperation:
+		return "copy"
+	case TrashOperation:
+		return "trash"
+	case ZipOperation:
+		return "zip"
+	case UnzipOperation:
+		return "unzip"
+	case ExtractOperation:
+		return "extract"
+	}
+
+	return "unknown"
+}
+
+// Operation is a file operation which was done, along with what is needed to undo it.
+type Operation struct {
+	// Kind is the kind of operation.
+	Kind OperationKind
+	// Source is the item the operation was done on.
+	Source string
+	// Created holds the paths the operation created, which are moved to the
+	// trash when it is undone, or removed on backends other than the local
+	// disk. Renames create their target.
+	Created []string
+
+	trashItem TrashItem
+	modTimes  []time.Time
+}
+
+// Batch is a group of file operations which are undone together, such as an
+// operation done on every selected item. Each method does an operation and
+// records it when it succeeds, or when it failed after creating something.
+type Batch struct {
+	fsys       FS
+	operations []Operation
+}
+
+// NewBatch returns an empty batch of operations done on a backend.
+func NewBatch(fsys FS) *Batch {
+	return &Batch{fsys: fsys}
+}
+
+// Operations returns the operations done in the batch, in the order they were done.
+func (b *Batch) Operations() []Operation {
+	return slices.Clone(b.operations)
+}
+
+// record adds an operation to the batch. Backends without a trash keep when what
+// was created was last modified, so that undoing does not remove later changes.
+func (b *Batch) record(operation Operation) {
+	if !IsOS(b.fsys) && operation.Kind != RenameOperation {
+		for _, name := range operation.Created {
+			modTime, _ := latestModTime(b.fsys, name)
+			operation.modTimes = append(operation.modTimes, modTime)
+		}
+	}
+
+	b.operations = append(b.operations, operation)
+}
+
+// latestModTime returns when a file, or anything within a directory, was last modified.
+func latestModTime(fsys FS, name string) (time.Time, error) {
+	var latest time.Time
+
+	err := WalkDirFS(fsys, name, func(_ string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+
+		if info.ModTime().After(latest) {
+			latest = info.ModTime()
+		}
+
+		return nil
+	})
+
+	return latest, errors.Unwrap(err)
+}
+
+// CreateFile creates a file given a name.
+func (b *Batch) CreateFile(name string) error {
+	if _, err := b.fsys.Stat(name); err == nil {
+		return &fs.PathError{Op: "create", Path: name, Err: fs.ErrExist}
+	}
+
+	if err := CreateFileFS(b.fsys, name); err != nil {
+		return err
+	}
+
+	b.record(Operation{Kind: CreateOperation, Source: name, Created: []string{name}})
+
+	return nil
+}
+
+// CreateDirectory creates a new directory given a name.
+func (b *Batch) CreateDirectory(name string) error {
+	if _, err := b.fsys.Stat(name); err == nil {
+		return &fs.PathError{Op: "mkdir", Path: name, Err: fs.ErrExist}
+	}
+
+	if err := CreateDirectoryFS(b.fsys, name); err != nil {
+		return err
+	}
+
+	b.record(Operation{Kind: CreateOperation, Source: name, Created: []string{name}})
+
+	return nil
+}
+
+// Rename renames or moves a directory or file given a source and destination.
+func (b *Batch) Rename(src, dst string) error {
+	if err := RenameDirectoryItemFS(b.fsys, src, dst); err != nil {
+		return err
+	}
+
+	b.record(Operation{Kind: RenameOperation, Source: src, Created: []string{dst}})
+
+	return nil
+}
+
+// Copy copies a directory or file given a name, writing the copy into dir.
+func (b *Batch) Copy(name, dir string) error {
+	info, err := b.fsys.Stat(name)
+	if err != nil {
+		return errors.Unwrap(err)
+	}
+
+	var output string
+	if info.IsDir() {
+		output, err = copyDirectoryFS(b.fsys, name, dir)
+	} else {
+		output, err = copyFileFS(b.fsys, name, dir)
+	}
+
+	if output != "" {
+		b.record(Operation{Kind: CopyOperation, Source: name, Created: []string{output}})
+	}
+
+	return err
+}
+
+// Trash moves a directory or file to the trash, which is only available on the local disk.
+func (b *Batch) Trash(name string) error {
+	if !IsOS(b.fsys) {
+		return fmt.Errorf("can not move %s to the trash: %w", name, errors.ErrUnsupported)
+	}
+
+	item, err := trash(name)
+	if err != nil {
+		return err
+	}
+
+	b.record(Operation{Kind: TrashOperation, Source: item.OriginalPath, trashItem: item})
+
+	return nil
+}
+
+// Zip zips a directory or file given a name, writing the zip file into dir.
+func (b *Batch) Zip(name, dir string) error {
+	output, err := zipFS(b.fsys, name, dir)
+	if output != "" {
+		b.record(Operation{Kind: ZipOperation, Source: name, Created: []string{output}})
+	}
+
+	return err
+}
+
+// Unzip unzips a zip file given a name next to itself, failing rather than
+// overwriting files which already exist. Undoing it removes what was extracted.
+func (b *Batch) Unzip(name string) error {
+	created, err := unzipFS(b.fsys, name)
+	if len(created) > 0 {
+		b.record(Operation{Kind: UnzipOperation, Source: name, Created: created})
+	}
+
+	return err
+}
+
+// Extract copies files or directories out of another backend, such as an archive,
+// into dir. Undoing it removes what was extracted.
+func (b *Batch) Extract(src FS, names []string, dir string) error {
+	for _, name := range names {
+		created, err := extractFS(src, name, b.fsys, dir)
+		if len(created) > 0 {
+			b.record(Operation{Kind: ExtractOperation, Source: name, Created: created})
+		}
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// undo reverts an operation, forgetting the items created by it as they are removed
+// so that undoing again after an error carries on where it stopped.
+func (b *Batch) undo(operation *Operation) error {
+	switch operation.Kind {
+	case RenameOperation:
+		// Renaming back must not replace anything created at the old name since.
+		if _, err := b.fsys.Stat(operation.Source); err == nil {
+			return fmt.Errorf("can not undo rename: %w", &fs.PathError{Op: "rename", Path: operation.Source, Err: fs.ErrExist})
+		}
+
+		return RenameDirectoryItemFS(b.fsys, operation.Created[0], operation.Source)
+	case TrashOperation:
+		return RestoreTrash(operation.trashItem)
+	}
+
+	var missing []error
+
+	// Items are removed in reverse order so that files go before their directories.
+	for len(operation.Created) > 0 {
+		last := len(operation.Created) - 1
+
+		var modTime time.Time
+		if last < len(operation.modTimes) {
+			modTime = operation.modTimes[last]
+		}
+
+		if err := b.removeCreated(operation.Created[last], modTime); err != nil {
+			if !errors.Is(err, fs.ErrNotExist) {
+				return err
+			}
+
+			missing = append(missing, err)
+		}
+
+		operation.Created = operation.Created[:last]
+		operation.modTimes = operation.modTimes[:min(last, len(operation.modTimes))]
+	}
+
+	return errors.Join(missing...)
+}
+
+// removeCreated removes an item created by an operation. Items on the local disk are
+// moved to the trash so that they can be recovered, on other backends they are only
+// removed when nothing was modified since the operation.
+func (b *Batch) removeCreated(name string, modTime time.Time) error {
+	if IsOS(b.fsys) {
+		_, err := trash(name)
+
+		return err
+	}
+
+	latest, err := latestModTime(b.fsys, name)
+	if err != nil {
+		return err
+	}
+
+	if !latest.Equal(modTime) {
+		return fmt.Errorf("can not undo, %s was modified since", name)
+	}
+
+	return DeleteDirectoryFS(b.fsys, name)
+}
+
+// Journal records batches of file operations so that they can be undone,
+// most recent first. A journal can be shared by several models.
+type Journal struct {
+	mu      sync.Mutex
+	batches []*Batch
+	limit   int
+}
+
+// NewJournal returns an empty journal which keeps up to limit batches,
+// dropping the oldest ones. There is no limit when it is zero.
+func NewJournal(limit int) *Journal {
+	return &Journal{limit: limit}
+}
+
+// Record adds a batch to the journal, empty batches are ignored.
+func (j *Journal) Record(batch *Batch) {
+	if j == nil || batch == nil || len(batch.operations) == 0 {
+		return
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	j.batches = append(j.batches, batch)
+	if j.limit > 0 && len(j.batches) > j.limit {
+		j.batches = slices.Delete(j.batches, 0, len(j.batches)-j.limit)
+	}
+}
+
+// Len returns the number of batches which can be undone.
+func (j *Journal) Len() int {
+	if j == nil {
+		return 0
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	return len(j.batches)
+}
+
+// Undo reverts the most recent batch, undoing its operations in reverse order.
+// Operations on items which no longer exist are dropped and reported in the
+// returned error. When an operation can not be undone for another reason the
+// ones which remain are kept in the journal, so that undoing can be tried
+// again once the problem is fixed, or the batch can be discarded.
+func (j *Journal) Undo() error {
+	if j == nil {
+		return errors.New("nothing to undo")
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if len(j.batches) == 0 {
+		return errors.New("nothing to undo")
+	}
+
+	batch := j.batches[len(j.batches)-1]
+
+	var missing []error
+
+	for len(batch.operations) > 0 {
+		operation := &batch.operations[len(batch.operations)-1]
+		if err := batch.undo(operation); err != nil {
+			if !errors.Is(err, fs.ErrNotExist) {
+				return err
+			}
+
+			missing = append(missing, fmt.Errorf("can not undo %s of %s: %w", operation.Kind, operation.Source, err))
+		}
+
+		batch.operations = batch.operations[:len(batch.operations)-1]
+	}
+
+	j.batches = j.batches[:len(j.batches)-1]
+
+	return errors.Join(missing...)
+}
+
+// Discard removes the most recent batch without undoing it, such as one which
+// can not be undone. It reports if there was a batch to remove.
+func (j *Journal) Discard() bool {
+	if j == nil {
+		return false
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if len(j.batches) == 0 {
+		return false
+	}
+
+	j.batches = j.batches[:len(j.batches)-1]
+
+	return true
+}
+
+// Clear removes every batch from the journal.
+func (j *Journal) Clear() {
+	if j == nil {
+		return
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	j.batches = nil
+}
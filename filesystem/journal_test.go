@@ -0,0 +1,440 @@
+package filesystem
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// memTree returns every item in a MemFS, mapping files to their content
+// and directories to a trailing slash.
+func memTree(t *testing.T, fsys *MemFS) map[string]string {
+	t.Helper()
+
+	tree := make(map[string]string)
+
+	err := WalkDirFS(fsys, "/", func(name string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if entry.IsDir() {
+			tree[name] = "/"
+
+			return nil
+		}
+
+		data, err := fsys.ReadFile(name)
+		tree[name] = string(data)
+
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return tree
+}
+
+// writeMemFile creates a file in a MemFS along with the directories containing it.
+func writeMemFile(t *testing.T, fsys *MemFS, name, content string) {
+	t.Helper()
+
+	if err := fsys.MkdirAll(filepath.Dir(name), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := fsys.WriteFile(name, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// zipMemFile zips name into a zip file at output.
+func zipMemFile(t *testing.T, fsys *MemFS, name, output string) {
+	t.Helper()
+
+	zipped, err := zipFS(fsys, name, filepath.Dir(output))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := fsys.Rename(zipped, output); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestJournalUndo(t *testing.T) {
+	tests := []struct {
+		name  string
+		setup func(t *testing.T, fsys *MemFS)
+		do    func(batch *Batch) error
+		want  OperationKind
+	}{
+		{
+			name: "create file",
+			do:   func(batch *Batch) error { return batch.CreateFile("/d/new.txt") },
+			want: CreateOperation,
+		},
+		{
+			name: "create directory",
+			do:   func(batch *Batch) error { return batch.CreateDirectory("/d/new") },
+			want: CreateOperation,
+		},
+		{
+			name: "rename",
+			do:   func(batch *Batch) error { return batch.Rename("/d/notes.txt", "/d/renamed.txt") },
+			want: RenameOperation,
+		},
+		{
+			name: "move a directory",
+			do:   func(batch *Batch) error { return batch.Rename("/d/src", "/other/src") },
+			want: RenameOperation,
+		},
+		{
+			name: "copy file",
+			do:   func(batch *Batch) error { return batch.Copy("/d/notes.txt", "/d") },
+			want: CopyOperation,
+		},
+		{
+			name: "copy directory",
+			do:   func(batch *Batch) error { return batch.Copy("/d/src", "/other") },
+			want: CopyOperation,
+		},
+		{
+			name: "zip",
+			do:   func(batch *Batch) error { return batch.Zip("/d/src", "/d") },
+			want: ZipOperation,
+		},
+		{
+			name: "unzip",
+			setup: func(t *testing.T, fsys *MemFS) {
+				t.Helper()
+				zipMemFile(t, fsys, "/d/src", "/other/src.zip")
+			},
+			do:   func(batch *Batch) error { return batch.Unzip("/other/src.zip") },
+			want: UnzipOperation,
+		},
+		{
+			name: "unzip into an existing directory",
+			setup: func(t *testing.T, fsys *MemFS) {
+				t.Helper()
+				zipMemFile(t, fsys, "/d/src", "/d/src.zip")
+
+				for _, name := range []string{"/d/src/main.go", "/d/src/lib"} {
+					if err := fsys.RemoveAll(name); err != nil {
+						t.Fatal(err)
+					}
+				}
+			},
+			do:   func(batch *Batch) error { return batch.Unzip("/d/src.zip") },
+			want: UnzipOperation,
+		},
+		{
+			name: "extract",
+			do: func(batch *Batch) error {
+				src := NewMemFS()
+				if err := src.MkdirAll("/archive/docs/guide", 0o755); err != nil {
+					return err
+				}
+
+				if err := src.WriteFile("/archive/docs/guide/intro.md", []byte("intro"), 0o644); err != nil {
+					return err
+				}
+
+				return batch.Extract(src, []string{"/archive/docs"}, "/other")
+			},
+			want: ExtractOperation,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fsys := NewMemFS()
+			writeMemFile(t, fsys, "/d/notes.txt", "notes")
+			writeMemFile(t, fsys, "/d/src/main.go", "package main")
+			writeMemFile(t, fsys, "/d/src/lib/lib.go", "package lib")
+
+			if err := fsys.Mkdir("/other", 0o755); err != nil {
+				t.Fatal(err)
+			}
+
+			if tt.setup != nil {
+				tt.setup(t, fsys)
+			}
+
+			before := memTree(t, fsys)
+
+			batch := NewBatch(fsys)
+			if err := tt.do(batch); err != nil {
+				t.Fatal(err)
+			}
+
+			if operations := batch.Operations(); len(operations) != 1 || operations[0].Kind != tt.want {
+				t.Fatalf("Operations() = %+v, want one %s operation", operations, tt.want)
+			}
+
+			if reflect.DeepEqual(memTree(t, fsys), before) {
+				t.Fatal("the operation did not change anything")
+			}
+
+			journal := NewJournal(0)
+			journal.Record(batch)
+
+			if err := journal.Undo(); err != nil {
+				t.Fatal(err)
+			}
+
+			if got := memTree(t, fsys); !reflect.DeepEqual(got, before) {
+				t.Errorf("after undoing = %v, want %v", got, before)
+			}
+
+			if journal.Len() != 0 {
+				t.Errorf("Len() = %d after undoing, want 0", journal.Len())
+			}
+		})
+	}
+}
+
+func TestJournalUndoTrash(t *testing.T) {
+	setupTrash(t)
+
+	dir := t.TempDir()
+	created := filepath.Join(dir, "created.txt")
+	trashed := filepath.Join(dir, "trashed.txt")
+	writeTestFile(t, trashed, "content")
+
+	batch := NewBatch(OSFS{})
+	if err := batch.CreateFile(created); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := batch.Trash(trashed); err != nil {
+		t.Fatal(err)
+	}
+
+	journal := NewJournal(0)
+	journal.Record(batch)
+
+	if err := journal.Undo(); err != nil {
+		t.Fatal(err)
+	}
+
+	if data, err := os.ReadFile(trashed); err != nil || string(data) != "content" {
+		t.Errorf("restored file = %q, %v, want %q", data, err, "content")
+	}
+
+	// Created items are moved to the trash rather than removed.
+	if _, err := os.Lstat(created); !os.IsNotExist(err) {
+		t.Errorf("the created file is still at %s", created)
+	}
+
+	items, err := ListTrash()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	found := false
+
+	for _, item := range items {
+		found = found || item.OriginalPath == created
+	}
+
+	if !found {
+		t.Errorf("the created file was not moved to the trash")
+	}
+}
+
+func TestJournalUndoResume(t *testing.T) {
+	fsys := NewMemFS()
+	writeMemFile(t, fsys, "/d/a.txt", "a")
+
+	batch := NewBatch(fsys)
+	if err := batch.CreateFile("/d/new.txt"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := batch.Rename("/d/a.txt", "/d/b.txt"); err != nil {
+		t.Fatal(err)
+	}
+
+	journal := NewJournal(0)
+	journal.Record(batch)
+
+	// The old name has been reused, so the rename can not be undone.
+	writeMemFile(t, fsys, "/d/a.txt", "blocker")
+
+	err := journal.Undo()
+	if !errors.Is(err, fs.ErrExist) {
+		t.Fatalf("Undo() = %v, want %v", err, fs.ErrExist)
+	}
+
+	if journal.Len() != 1 {
+		t.Fatalf("Len() = %d after a failed undo, want 1", journal.Len())
+	}
+
+	if _, err := fsys.Stat("/d/new.txt"); err != nil {
+		t.Errorf("an operation before the failed one was undone: %v", err)
+	}
+
+	if err := fsys.Remove("/d/a.txt"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := journal.Undo(); err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]string{"/": "/", "/d": "/", "/d/a.txt": "a"}
+	if got := memTree(t, fsys); !reflect.DeepEqual(got, want) {
+		t.Errorf("after undoing = %v, want %v", got, want)
+	}
+
+	if journal.Len() != 0 {
+		t.Errorf("Len() = %d after undoing, want 0", journal.Len())
+	}
+}
+
+func TestJournalUndoPartialFailure(t *testing.T) {
+	fsys := NewMemFS()
+
+	if err := fsys.Mkdir("/d", 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	batch := NewBatch(fsys)
+
+	for _, name := range []string{"/d/first.txt", "/d/second.txt", "/d/third.txt"} {
+		if err := batch.CreateFile(name); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	journal := NewJournal(0)
+	journal.Record(batch)
+
+	// The first file was modified and the third one removed since.
+	writeMemFile(t, fsys, "/d/first.txt", "changed")
+
+	if err := fsys.Remove("/d/third.txt"); err != nil {
+		t.Fatal(err)
+	}
+
+	err := journal.Undo()
+	if err == nil || errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("Undo() = %v, want an error about the modified file", err)
+	}
+
+	if journal.Len() != 1 {
+		t.Fatalf("Len() = %d after a failed undo, want 1", journal.Len())
+	}
+
+	if _, err := fsys.Stat("/d/second.txt"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("the second file was not removed: %v", err)
+	}
+
+	if data, err := fsys.ReadFile("/d/first.txt"); err != nil || string(data) != "changed" {
+		t.Errorf("modified file = %q, %v, want %q", data, err, "changed")
+	}
+
+	// Undoing again only retries the operation which failed.
+	if err := journal.Undo(); err == nil {
+		t.Fatal("Undo() removed a file modified since it was created")
+	}
+
+	if !journal.Discard() {
+		t.Fatal("Discard() = false, want true")
+	}
+
+	if journal.Len() != 0 || journal.Discard() {
+		t.Errorf("Len() = %d after discarding, want 0", journal.Len())
+	}
+}
+
+func TestJournalUndoMissing(t *testing.T) {
+	fsys := NewMemFS()
+
+	if err := fsys.Mkdir("/d", 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	batch := NewBatch(fsys)
+
+	for _, name := range []string{"/d/kept.txt", "/d/gone.txt"} {
+		if err := batch.CreateFile(name); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	journal := NewJournal(0)
+	journal.Record(batch)
+
+	if err := fsys.Remove("/d/gone.txt"); err != nil {
+		t.Fatal(err)
+	}
+
+	// Items which no longer exist are reported, the rest of the batch is still undone.
+	if err := journal.Undo(); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("Undo() = %v, want %v", err, fs.ErrNotExist)
+	}
+
+	if _, err := fsys.Stat("/d/kept.txt"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("the other file was not removed: %v", err)
+	}
+
+	if journal.Len() != 0 {
+		t.Errorf("Len() = %d after undoing, want 0", journal.Len())
+	}
+}
+
+func TestUnzipDoesNotOverwrite(t *testing.T) {
+	fsys := NewMemFS()
+	writeMemFile(t, fsys, "/d/src/main.go", "package main")
+
+	zipMemFile(t, fsys, "/d/src", "/d/src.zip")
+	writeMemFile(t, fsys, "/d/src/main.go", "changed")
+
+	if err := UnzipFS(fsys, "/d/src.zip"); !errors.Is(err, fs.ErrExist) {
+		t.Errorf("UnzipFS() = %v, want %v", err, fs.ErrExist)
+	}
+
+	if data, err := fsys.ReadFile("/d/src/main.go"); err != nil || string(data) != "changed" {
+		t.Errorf("existing file = %q, %v, want %q", data, err, "changed")
+	}
+}
+
+func TestJournalLimit(t *testing.T) {
+	fsys := NewMemFS()
+	journal := NewJournal(2)
+
+	for _, name := range []string{"/a", "/b", "/c"} {
+		batch := NewBatch(fsys)
+		if err := batch.CreateDirectory(name); err != nil {
+			t.Fatal(err)
+		}
+
+		journal.Record(batch)
+	}
+
+	journal.Record(NewBatch(fsys))
+
+	if journal.Len() != 2 {
+		t.Fatalf("Len() = %d, want 2", journal.Len())
+	}
+
+	for range 2 {
+		if err := journal.Undo(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := journal.Undo(); err == nil {
+		t.Error("Undo() with an empty journal did not fail")
+	}
+
+	if _, err := fsys.Stat("/a"); err != nil {
+		t.Errorf("the oldest batch was undone: %v", err)
+	}
+}
@@ -0,0 +1,384 @@
+package filesystem
+
+import (
+	"bytes"
+	"io"
+	"io/fs"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// memNode is a single file or directory in a MemFS.
+type memNode struct {
+	name    string
+	data    []byte
+	mode    fs.FileMode
+	modTime time.Time
+}
+
+// memFileInfo describes a memNode.
+type memFileInfo struct {
+	node memNode
+}
+
+func (i memFileInfo) Name() string       { return i.node.name }
+func (i memFileInfo) Size() int64        { return int64(len(i.node.data)) }
+func (i memFileInfo) Mode() fs.FileMode  { return i.node.mode }
+func (i memFileInfo) ModTime() time.Time { return i.node.modTime }
+func (i memFileInfo) IsDir() bool        { return i.node.mode.IsDir() }
+func (i memFileInfo) Sys() any           { return nil }
+
+// memFile is an open file or directory in a MemFS.
+type memFile struct {
+	*bytes.Reader
+	info    memFileInfo
+	entries []fs.DirEntry
+}
+
+// Stat returns information about the file.
+func (f *memFile) Stat() (fs.FileInfo, error) {
+	return f.info, nil
+}
+
+// Close closes the file.
+func (f *memFile) Close() error {
+	return nil
+}
+
+// ReadDir reads the entries of an open directory.
+func (f *memFile) ReadDir(n int) ([]fs.DirEntry, error) {
+	if n <= 0 {
+		entries := f.entries
+		f.entries = nil
+
+		return entries, nil
+	}
+
+	if len(f.entries) == 0 {
+		return nil, io.EOF
+	}
+
+	n = min(n, len(f.entries))
+	entries := f.entries[:n]
+	f.entries = f.entries[n:]
+
+	return entries, nil
+}
+
+// MemFS is an in-memory backend which is safe for concurrent use.
+// Paths are slash separated and rooted at "/", relative paths are
+// resolved against the root.
+type MemFS struct {
+	mu    sync.RWMutex
+	nodes map[string]memNode
+}
+
+// NewMemFS creates an empty in-memory backend containing only the root directory.
+func NewMemFS() *MemFS {
+	return &MemFS{
+		nodes: map[string]memNode{
+			"/": {name: "/", mode: fs.ModeDir | 0o755, modTime: time.Now()},
+		},
+	}
+}
+
+// memPath cleans a path into the form used as a key in a MemFS.
+func memPath(name string) string {
+	return path.Clean("/" + filepath.ToSlash(name))
+}
+
+// pathError creates an error for an operation on a path.
+func pathError(op, name string, err error) error {
+	return &fs.PathError{Op: op, Path: name, Err: err}
+}
+
+// checkParent returns an error if the parent of a path is not a directory.
+func (m *MemFS) checkParent(op, name string) error {
+	parent, ok := m.nodes[path.Dir(name)]
+	if !ok {
+		return pathError(op, name, fs.ErrNotExist)
+	}
+
+	if !parent.mode.IsDir() {
+		return pathError(op, name, fs.ErrInvalid)
+	}
+
+	return nil
+}
+
+// children returns the paths of the direct children of a directory, sorted by name.
+func (m *MemFS) children(name string) []string {
+	var children []string
+
+	for p := range m.nodes {
+		if p != "/" && path.Dir(p) == name {
+			children = append(children, p)
+		}
+	}
+
+	slices.Sort(children)
+
+	return children
+}
+
+// ReadDir returns the entries of a directory sorted by name.
+func (m *MemFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	name = memPath(name)
+
+	node, ok := m.nodes[name]
+	if !ok {
+		return nil, pathError("readdir", name, fs.ErrNotExist)
+	}
+
+	if !node.mode.IsDir() {
+		return nil, pathError("readdir", name, fs.ErrInvalid)
+	}
+
+	children := m.children(name)
+	entries := make([]fs.DirEntry, 0, len(children))
+
+	for _, child := range children {
+		entries = append(entries, fs.FileInfoToDirEntry(memFileInfo{node: m.nodes[child]}))
+	}
+
+	return entries, nil
+}
+
+// Stat returns information about a file.
+func (m *MemFS) Stat(name string) (fs.FileInfo, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	node, ok := m.nodes[memPath(name)]
+	if !ok {
+		return nil, pathError("stat", name, fs.ErrNotExist)
+	}
+
+	return memFileInfo{node: node}, nil
+}
+
+// Open opens a file for reading.
+func (m *MemFS) Open(name string) (fs.File, error) {
+	info, err := m.Stat(name)
+	if err != nil {
+		return nil, err
+	}
+
+	file := &memFile{
+		Reader: bytes.NewReader(info.(memFileInfo).node.data),
+		info:   info.(memFileInfo),
+	}
+
+	if info.IsDir() {
+		file.entries, err = m.ReadDir(name)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return file, nil
+}
+
+// ReadFile returns the contents of a file.
+func (m *MemFS) ReadFile(name string) ([]byte, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	node, ok := m.nodes[memPath(name)]
+	if !ok {
+		return nil, pathError("open", name, fs.ErrNotExist)
+	}
+
+	if node.mode.IsDir() {
+		return nil, pathError("read", name, fs.ErrInvalid)
+	}
+
+	return bytes.Clone(node.data), nil
+}
+
+// WriteFile writes data to a file, creating it if it does not exist.
+func (m *MemFS) WriteFile(name string, data []byte, perm fs.FileMode) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	name = memPath(name)
+
+	if err := m.checkParent("open", name); err != nil {
+		return err
+	}
+
+	if node, ok := m.nodes[name]; ok {
+		if node.mode.IsDir() {
+			return pathError("open", name, fs.ErrInvalid)
+		}
+
+		perm = node.mode
+	}
+
+	m.nodes[name] = memNode{
+		name:    path.Base(name),
+		data:    bytes.Clone(data),
+		mode:    perm.Perm(),
+		modTime: time.Now(),
+	}
+
+	return nil
+}
+
+// Rename renames or moves a file or directory along with everything it contains.
+// As with rename(2), a file can only replace a file and a directory an empty directory.
+func (m *MemFS) Rename(oldpath, newpath string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	oldpath, newpath = memPath(oldpath), memPath(newpath)
+
+	source, ok := m.nodes[oldpath]
+	if !ok || oldpath == "/" {
+		return pathError("rename", oldpath, fs.ErrNotExist)
+	}
+
+	if err := m.checkParent("rename", newpath); err != nil {
+		return err
+	}
+
+	if oldpath == newpath {
+		return nil
+	}
+
+	if strings.HasPrefix(newpath, oldpath+"/") {
+		return pathError("rename", newpath, fs.ErrInvalid)
+	}
+
+	if target, ok := m.nodes[newpath]; ok {
+		switch {
+		case source.mode.IsDir() && !target.mode.IsDir():
+			return pathError("rename", newpath, syscall.ENOTDIR)
+		case !source.mode.IsDir() && target.mode.IsDir():
+			return pathError("rename", newpath, syscall.EISDIR)
+		case target.mode.IsDir() && len(m.children(newpath)) > 0:
+			return pathError("rename", newpath, fs.ErrExist)
+		}
+	}
+
+	// The paths are collected first as nodes can not be added while ranging over them.
+	var paths []string
+
+	for p := range m.nodes {
+		if p == oldpath || strings.HasPrefix(p, oldpath+"/") {
+			paths = append(paths, p)
+		}
+	}
+
+	for _, p := range paths {
+		node := m.nodes[p]
+		delete(m.nodes, p)
+
+		p = newpath + strings.TrimPrefix(p, oldpath)
+		node.name = path.Base(p)
+		m.nodes[p] = node
+	}
+
+	return nil
+}
+
+// Remove removes a file or an empty directory.
+func (m *MemFS) Remove(name string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	name = memPath(name)
+
+	if _, ok := m.nodes[name]; !ok || name == "/" {
+		return pathError("remove", name, fs.ErrNotExist)
+	}
+
+	if len(m.children(name)) > 0 {
+		return pathError("remove", name, fs.ErrExist)
+	}
+
+	delete(m.nodes, name)
+
+	return nil
+}
+
+// RemoveAll removes a file or a directory and everything it contains.
+func (m *MemFS) RemoveAll(name string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	name = memPath(name)
+
+	for p := range m.nodes {
+		if p != "/" && (p == name || strings.HasPrefix(p, name+"/") || name == "/") {
+			delete(m.nodes, p)
+		}
+	}
+
+	return nil
+}
+
+// Mkdir creates a directory.
+func (m *MemFS) Mkdir(name string, perm fs.FileMode) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.mkdir(memPath(name), perm)
+}
+
+// mkdir creates a directory, the lock must be held by the caller.
+func (m *MemFS) mkdir(name string, perm fs.FileMode) error {
+	if _, ok := m.nodes[name]; ok {
+		return pathError("mkdir", name, fs.ErrExist)
+	}
+
+	if err := m.checkParent("mkdir", name); err != nil {
+		return err
+	}
+
+	m.nodes[name] = memNode{
+		name:    path.Base(name),
+		mode:    fs.ModeDir | perm.Perm(),
+		modTime: time.Now(),
+	}
+
+	return nil
+}
+
+// MkdirAll creates a directory along with any missing parents.
+func (m *MemFS) MkdirAll(name string, perm fs.FileMode) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	name = memPath(name)
+
+	var missing []string
+	for p := name; p != "/"; p = path.Dir(p) {
+		node, ok := m.nodes[p]
+		if ok {
+			if !node.mode.IsDir() {
+				return pathError("mkdir", p, fs.ErrInvalid)
+			}
+
+			break
+		}
+
+		missing = append(missing, p)
+	}
+
+	for i := len(missing) - 1; i >= 0; i-- {
+		if err := m.mkdir(missing[i], perm); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,294 @@
+package filesystem
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"reflect"
+	"syscall"
+	"testing"
+)
+
+func TestMemFSRename(t *testing.T) {
+	tests := []struct {
+		name    string
+		oldpath string
+		newpath string
+		wantErr error
+		want    map[string]string
+	}{
+		{
+			name:    "file onto a new name",
+			oldpath: "/file.txt",
+			newpath: "/moved.txt",
+			want: map[string]string{
+				"/": "/", "/moved.txt": "file", "/other.txt": "other",
+				"/dir": "/", "/dir/child.txt": "child", "/dir/sub": "/", "/dir/sub/deep.txt": "deep",
+				"/empty": "/", "/full": "/", "/full/kept.txt": "kept",
+			},
+		},
+		{
+			name:    "file replaces a file",
+			oldpath: "/file.txt",
+			newpath: "/other.txt",
+			want: map[string]string{
+				"/": "/", "/other.txt": "file",
+				"/dir": "/", "/dir/child.txt": "child", "/dir/sub": "/", "/dir/sub/deep.txt": "deep",
+				"/empty": "/", "/full": "/", "/full/kept.txt": "kept",
+			},
+		},
+		{
+			name:    "file onto a directory",
+			oldpath: "/file.txt",
+			newpath: "/empty",
+			wantErr: syscall.EISDIR,
+		},
+		{
+			name:    "directory onto a file",
+			oldpath: "/dir",
+			newpath: "/file.txt",
+			wantErr: syscall.ENOTDIR,
+		},
+		{
+			name:    "directory with children onto a new name",
+			oldpath: "/dir",
+			newpath: "/full/moved",
+			want: map[string]string{
+				"/": "/", "/file.txt": "file", "/other.txt": "other",
+				"/empty": "/", "/full": "/", "/full/kept.txt": "kept",
+				"/full/moved": "/", "/full/moved/child.txt": "child", "/full/moved/sub": "/", "/full/moved/sub/deep.txt": "deep",
+			},
+		},
+		{
+			name:    "directory replaces an empty directory",
+			oldpath: "/dir",
+			newpath: "/empty",
+			want: map[string]string{
+				"/": "/", "/file.txt": "file", "/other.txt": "other",
+				"/empty": "/", "/empty/child.txt": "child", "/empty/sub": "/", "/empty/sub/deep.txt": "deep",
+				"/full": "/", "/full/kept.txt": "kept",
+			},
+		},
+		{
+			name:    "directory onto a directory which is not empty",
+			oldpath: "/dir",
+			newpath: "/full",
+			wantErr: fs.ErrExist,
+		},
+		{
+			name:    "directory into itself",
+			oldpath: "/dir",
+			newpath: "/dir/sub/dir",
+			wantErr: fs.ErrInvalid,
+		},
+		{
+			name:    "missing source",
+			oldpath: "/missing",
+			newpath: "/moved",
+			wantErr: fs.ErrNotExist,
+		},
+		{
+			name:    "missing parent of the target",
+			oldpath: "/file.txt",
+			newpath: "/missing/file.txt",
+			wantErr: fs.ErrNotExist,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fsys := NewMemFS()
+			writeMemFile(t, fsys, "/file.txt", "file")
+			writeMemFile(t, fsys, "/other.txt", "other")
+			writeMemFile(t, fsys, "/dir/child.txt", "child")
+			writeMemFile(t, fsys, "/dir/sub/deep.txt", "deep")
+			writeMemFile(t, fsys, "/full/kept.txt", "kept")
+
+			if err := fsys.Mkdir("/empty", 0o755); err != nil {
+				t.Fatal(err)
+			}
+
+			before := memTree(t, fsys)
+
+			err := fsys.Rename(tt.oldpath, tt.newpath)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("Rename(%q, %q) = %v, want %v", tt.oldpath, tt.newpath, err, tt.wantErr)
+				}
+
+				if got := memTree(t, fsys); !reflect.DeepEqual(got, before) {
+					t.Errorf("a failed rename changed the tree to %v", got)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("Rename(%q, %q) = %v", tt.oldpath, tt.newpath, err)
+			}
+
+			if got := memTree(t, fsys); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("after renaming = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMemFSRenameKeepsNames(t *testing.T) {
+	fsys := NewMemFS()
+	writeMemFile(t, fsys, "/dir/child.txt", "child")
+
+	if err := fsys.Rename("/dir", "/moved"); err != nil {
+		t.Fatal(err)
+	}
+
+	for name, want := range map[string]string{"/moved": "moved", "/moved/child.txt": "child.txt"} {
+		info, err := fsys.Stat(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if info.Name() != want {
+			t.Errorf("Stat(%q).Name() = %q, want %q", name, info.Name(), want)
+		}
+	}
+}
+
+func TestMemFSRemoveAll(t *testing.T) {
+	tests := []struct {
+		name   string
+		remove string
+		want   map[string]string
+	}{
+		{
+			name:   "directory and everything it contains",
+			remove: "/dir",
+			want:   map[string]string{"/": "/", "/dir2": "/", "/dir2/file.txt": "file"},
+		},
+		{
+			name:   "file",
+			remove: "/dir/sub/deep.txt",
+			want: map[string]string{
+				"/": "/", "/dir": "/", "/dir/child.txt": "child", "/dir/sub": "/",
+				"/dir2": "/", "/dir2/file.txt": "file",
+			},
+		},
+		{
+			name:   "missing item",
+			remove: "/missing",
+			want: map[string]string{
+				"/": "/", "/dir": "/", "/dir/child.txt": "child", "/dir/sub": "/", "/dir/sub/deep.txt": "deep",
+				"/dir2": "/", "/dir2/file.txt": "file",
+			},
+		},
+		{
+			name:   "root keeps itself",
+			remove: "/",
+			want:   map[string]string{"/": "/"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fsys := NewMemFS()
+			writeMemFile(t, fsys, "/dir/child.txt", "child")
+			writeMemFile(t, fsys, "/dir/sub/deep.txt", "deep")
+			writeMemFile(t, fsys, "/dir2/file.txt", "file")
+
+			if err := fsys.RemoveAll(tt.remove); err != nil {
+				t.Fatalf("RemoveAll(%q) = %v", tt.remove, err)
+			}
+
+			if got := memTree(t, fsys); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("after removing = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMemFSMkdirAll(t *testing.T) {
+	fsys := NewMemFS()
+	writeMemFile(t, fsys, "/a/file.txt", "file")
+
+	if err := fsys.MkdirAll("/a/b/c", 0o700); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, name := range []string{"/a/b", "/a/b/c"} {
+		info, err := fsys.Stat(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !info.IsDir() || info.Mode().Perm() != 0o700 {
+			t.Errorf("Stat(%q) = %v, want a directory with permissions 700", name, info.Mode())
+		}
+	}
+
+	if err := fsys.MkdirAll("/a/b", 0o755); err != nil {
+		t.Errorf("MkdirAll of an existing directory = %v, want nil", err)
+	}
+
+	if err := fsys.MkdirAll("/a/file.txt/sub", 0o755); !errors.Is(err, fs.ErrInvalid) {
+		t.Errorf("MkdirAll below a file = %v, want %v", err, fs.ErrInvalid)
+	}
+
+	if _, err := fsys.Stat("/a/file.txt/sub"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("a directory was created below a file: %v", err)
+	}
+}
+
+func TestMemFSReadDirPaging(t *testing.T) {
+	fsys := NewMemFS()
+
+	for _, name := range []string{"/d/c", "/d/a", "/d/e", "/d/b", "/d/d"} {
+		writeMemFile(t, fsys, name, "")
+	}
+
+	file, err := fsys.Open("/d")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer file.Close()
+
+	dir, ok := file.(fs.ReadDirFile)
+	if !ok {
+		t.Fatal("an open directory does not implement fs.ReadDirFile")
+	}
+
+	var pages [][]string
+
+	for {
+		entries, err := dir.ReadDir(2)
+		if errors.Is(err, io.EOF) {
+			if len(entries) != 0 {
+				t.Errorf("ReadDir returned %d entries with io.EOF", len(entries))
+			}
+
+			break
+		}
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var page []string
+		for _, entry := range entries {
+			page = append(page, entry.Name())
+		}
+
+		pages = append(pages, page)
+	}
+
+	want := [][]string{{"a", "b"}, {"c", "d"}, {"e"}}
+	if !reflect.DeepEqual(pages, want) {
+		t.Errorf("pages = %v, want %v", pages, want)
+	}
+
+	// Reading everything that is left once the entries ran out returns nothing.
+	entries, err := dir.ReadDir(-1)
+	if err != nil || len(entries) != 0 {
+		t.Errorf("ReadDir(-1) = %v, %v, want no entries", entries, err)
+	}
+}
@@ -0,0 +1,11 @@
+//go:build !unix
+
+package filesystem
+
+import "io/fs"
+
+// GetOwner returns the names of the user and group owning a file,
+// ownership is not available on this platform so it is always empty.
+func GetOwner(info fs.FileInfo) (owner, group string) {
+	return "", ""
+}
@@ -0,0 +1,50 @@
+//go:build unix
+
+package filesystem
+
+import (
+	"io/fs"
+	"os/user"
+	"strconv"
+	"sync"
+	"syscall"
+)
+
+var (
+	ownerNamesMu sync.Mutex
+	userNames    = map[uint32]string{}
+	groupNames   = map[uint32]string{}
+)
+
+// GetOwner returns the names of the user and group owning a file.
+func GetOwner(info fs.FileInfo) (owner, group string) {
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return "", ""
+	}
+
+	ownerNamesMu.Lock()
+	defer ownerNamesMu.Unlock()
+
+	owner, ok = userNames[stat.Uid]
+	if !ok {
+		owner = strconv.FormatUint(uint64(stat.Uid), 10)
+		if u, err := user.LookupId(owner); err == nil {
+			owner = u.Username
+		}
+
+		userNames[stat.Uid] = owner
+	}
+
+	group, ok = groupNames[stat.Gid]
+	if !ok {
+		group = strconv.FormatUint(uint64(stat.Gid), 10)
+		if g, err := user.LookupGroupId(group); err == nil {
+			group = g.Name
+		}
+
+		groupNames[stat.Gid] = group
+	}
+
+	return owner, group
+}
@@ -0,0 +1,481 @@
+package filesystem
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// trashInfoTimeFormat is the layout of deletion dates in trash info files.
+const trashInfoTimeFormat = "2006-01-02T15:04:05"
+
+// TrashItem is a file or directory which was moved to the trash.
+type TrashItem struct {
+	// Name is the name of the item within the trash directory.
+	Name string
+	// OriginalPath is where the item was before it was moved to the trash.
+	OriginalPath string
+	// DeletionDate is when the item was moved to the trash.
+	DeletionDate time.Time
+	// TrashDirectory is the trash directory containing the item.
+	TrashDirectory string
+}
+
+// Path returns where the item is kept within the trash directory.
+func (i TrashItem) Path() string {
+	return filepath.Join(i.TrashDirectory, "files", i.Name)
+}
+
+// infoPath returns the trash info file describing the item.
+func (i TrashItem) infoPath() string {
+	return filepath.Join(i.TrashDirectory, "info", i.Name+".trashinfo")
+}
+
+// HomeTrashDirectory returns the trash directory of the user, which is
+// in $XDG_DATA_HOME or ~/.local/share when it is not set.
+func HomeTrashDirectory() (string, error) {
+	dataHome := os.Getenv("XDG_DATA_HOME")
+	if dataHome == "" {
+		home, err := GetHomeDirectory()
+		if err != nil {
+			return "", err
+		}
+
+		dataHome = filepath.Join(home, ".local", "share")
+	}
+
+	return filepath.Join(dataHome, "Trash"), nil
+}
+
+// Trash moves a file or directory to the trash following the freedesktop.org
+// trash specification. Items on the same filesystem as the home directory go to
+// the trash of the user, others go to a trash directory at the top of their mount.
+func Trash(name string) error {
+	_, err := trash(name)
+
+	return err
+}
+
+// trash moves a file or directory to the trash, returning the item in the trash.
+func trash(name string) (TrashItem, error) {
+	path, err := filepath.Abs(name)
+	if err != nil {
+		return TrashItem{}, errors.Unwrap(err)
+	}
+
+	info, err := os.Lstat(path)
+	if err != nil {
+		return TrashItem{}, errors.Unwrap(err)
+	}
+
+	trashDirectory, topDirectory, err := trashDirectoryFor(path, info)
+	if err != nil {
+		return TrashItem{}, err
+	}
+
+	// Items in a trash directory at the top of a mount are recorded
+	// relative to it, so that they survive it being mounted elsewhere.
+	originalPath := path
+	if topDirectory != "" {
+		originalPath, err = filepath.Rel(topDirectory, path)
+		if err != nil {
+			return TrashItem{}, err
+		}
+	}
+
+	item, err := moveToTrash(path, originalPath, trashDirectory)
+	item.OriginalPath = path
+
+	return item, err
+}
+
+// trashDirectoryFor returns the trash directory an item is moved to, along with the
+// top of the mount containing it when it is not the trash directory of the user.
+func trashDirectoryFor(path string, info fs.FileInfo) (trashDirectory, topDirectory string, err error) {
+	homeTrash, err := HomeTrashDirectory()
+	if err != nil {
+		return "", "", err
+	}
+
+	if err := makeTrashDirectory(homeTrash); err != nil {
+		return "", "", err
+	}
+
+	device, ok := deviceID(info)
+	if !ok {
+		return homeTrash, "", nil
+	}
+
+	homeTrashInfo, err := os.Stat(homeTrash)
+	if err != nil {
+		return "", "", errors.Unwrap(err)
+	}
+
+	if homeDevice, _ := deviceID(homeTrashInfo); homeDevice == device {
+		return homeTrash, "", nil
+	}
+
+	topDirectory = mountPoint(path, device)
+	for _, trashDirectory := range topTrashDirectories(topDirectory) {
+		if err := makeTrashDirectory(trashDirectory); err == nil {
+			return trashDirectory, topDirectory, nil
+		}
+	}
+
+	return "", "", fmt.Errorf("no trash directory can be used on the filesystem mounted at %s", topDirectory)
+}
+
+// topTrashDirectories returns the trash directories which can be used at the top of
+// a mount. The shared .Trash directory is only used when it has the sticky bit set
+// and is not a link, as otherwise other users could read or replace trashed items.
+func topTrashDirectories(topDirectory string) []string {
+	uid := strconv.Itoa(os.Getuid())
+
+	var trashDirectories []string
+
+	sharedTrash := filepath.Join(topDirectory, ".Trash")
+	if info, err := os.Lstat(sharedTrash); err == nil && info.IsDir() && info.Mode()&fs.ModeSticky != 0 {
+		trashDirectories = append(trashDirectories, filepath.Join(sharedTrash, uid))
+	}
+
+	return append(trashDirectories, filepath.Join(topDirectory, ".Trash-"+uid))
+}
+
+// makeTrashDirectory creates a trash directory if it does not exist.
+func makeTrashDirectory(trashDirectory string) error {
+	for _, dir := range []string{"files", "info"} {
+		if err := os.MkdirAll(filepath.Join(trashDirectory, dir), 0o700); err != nil {
+			return errors.Unwrap(err)
+		}
+	}
+
+	return nil
+}
+
+// mountPoint returns the top directory of the mount containing path.
+func mountPoint(path string, device uint64) string {
+	dir := filepath.Dir(path)
+
+	for {
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return dir
+		}
+
+		info, err := os.Stat(parent)
+		if err != nil {
+			return dir
+		}
+
+		if parentDevice, _ := deviceID(info); parentDevice != device {
+			return dir
+		}
+
+		dir = parent
+	}
+}
+
+// moveToTrash moves an item into a trash directory under a name which is not yet
+// used. The info file is created first so that concurrent moves can not pick the
+// same name, and is removed again when the item can not be moved.
+func moveToTrash(path, originalPath, trashDirectory string) (TrashItem, error) {
+	base := filepath.Base(path)
+	extension := filepath.Ext(base)
+	deletionDate := time.Now()
+
+	info := fmt.Sprintf("[Trash Info]\nPath=%s\nDeletionDate=%s\n",
+		(&url.URL{Path: originalPath}).EscapedPath(), deletionDate.Format(trashInfoTimeFormat))
+
+	for i := 1; ; i++ {
+		name := base
+		if i > 1 {
+			name = fmt.Sprintf("%s.%d%s", strings.TrimSuffix(base, extension), i, extension)
+		}
+
+		item := TrashItem{Name: name, DeletionDate: deletionDate, TrashDirectory: trashDirectory}
+
+		infoFile, err := os.OpenFile(item.infoPath(), os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o600)
+		if errors.Is(err, fs.ErrExist) {
+			continue
+		}
+
+		if err != nil {
+			return TrashItem{}, errors.Unwrap(err)
+		}
+
+		_, err = infoFile.WriteString(info)
+		if closeErr := infoFile.Close(); err == nil {
+			err = closeErr
+		}
+
+		if err != nil {
+			_ = os.Remove(item.infoPath())
+
+			return TrashItem{}, errors.Unwrap(err)
+		}
+
+		// Skip names left behind in the files directory without an info file.
+		if _, err := os.Lstat(item.Path()); err == nil {
+			_ = os.Remove(item.infoPath())
+
+			continue
+		}
+
+		if err := os.Rename(path, item.Path()); err != nil {
+			_ = os.Remove(item.infoPath())
+
+			return TrashItem{}, errors.Unwrap(err)
+		}
+
+		return item, nil
+	}
+}
+
+// TrashDirectories returns the trash directory of the user along with
+// any trash directories which exist at the top of other mounts.
+func TrashDirectories() []string {
+	var trashDirectories []string
+
+	if homeTrash, err := HomeTrashDirectory(); err == nil {
+		trashDirectories = append(trashDirectories, homeTrash)
+	}
+
+	for _, topDirectory := range mountPoints() {
+		for _, trashDirectory := range topTrashDirectories(topDirectory) {
+			if info, err := os.Stat(filepath.Join(trashDirectory, "info")); err == nil && info.IsDir() {
+				trashDirectories = append(trashDirectories, trashDirectory)
+			}
+		}
+	}
+
+	return slices.Compact(trashDirectories)
+}
+
+// mountPoints returns the mounted filesystems, which are only
+// known on platforms where /proc/self/mounts is available.
+func mountPoints() []string {
+	data, err := os.ReadFile("/proc/self/mounts")
+	if err != nil {
+		return nil
+	}
+
+	var mounts []string
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 2 {
+			continue
+		}
+
+		mounts = append(mounts, unescapeMountPath(fields[1]))
+	}
+
+	return mounts
+}
+
+// unescapeMountPath decodes the octal escapes used for spaces
+// and other special characters in /proc/self/mounts.
+func unescapeMountPath(path string) string {
+	var unescaped strings.Builder
+
+	for i := 0; i < len(path); i++ {
+		if path[i] == '\\' && i+3 < len(path) {
+			if c, err := strconv.ParseUint(path[i+1:i+4], 8, 8); err == nil {
+				unescaped.WriteByte(byte(c))
+				i += 3
+
+				continue
+			}
+		}
+
+		unescaped.WriteByte(path[i])
+	}
+
+	return unescaped.String()
+}
+
+// ListTrash returns the items in every trash directory, most recently deleted first.
+func ListTrash() ([]TrashItem, error) {
+	var items []TrashItem
+
+	for _, trashDirectory := range TrashDirectories() {
+		trashItems, err := listTrashDirectory(trashDirectory)
+		if err != nil {
+			return nil, err
+		}
+
+		items = append(items, trashItems...)
+	}
+
+	slices.SortStableFunc(items, func(a, b TrashItem) int {
+		return b.DeletionDate.Compare(a.DeletionDate)
+	})
+
+	return items, nil
+}
+
+// listTrashDirectory returns the items in a trash directory, skipping info
+// files which can not be read or which describe items which are gone.
+func listTrashDirectory(trashDirectory string) ([]TrashItem, error) {
+	entries, err := os.ReadDir(filepath.Join(trashDirectory, "info"))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, errors.Unwrap(err)
+	}
+
+	// Relative paths are relative to the top of the mount containing the trash directory.
+	topDirectory := filepath.Dir(trashDirectory)
+	if filepath.Base(topDirectory) == ".Trash" {
+		topDirectory = filepath.Dir(topDirectory)
+	}
+
+	items := make([]TrashItem, 0, len(entries))
+
+	for _, entry := range entries {
+		name, ok := strings.CutSuffix(entry.Name(), ".trashinfo")
+		if !ok || entry.IsDir() {
+			continue
+		}
+
+		item := TrashItem{Name: name, TrashDirectory: trashDirectory}
+
+		data, err := os.ReadFile(item.infoPath())
+		if err != nil {
+			continue
+		}
+
+		item.OriginalPath, item.DeletionDate, err = ParseTrashInfo(data)
+		if err != nil {
+			continue
+		}
+
+		if !filepath.IsAbs(item.OriginalPath) {
+			item.OriginalPath = filepath.Join(topDirectory, item.OriginalPath)
+		}
+
+		if _, err := os.Lstat(item.Path()); err != nil {
+			continue
+		}
+
+		items = append(items, item)
+	}
+
+	return items, nil
+}
+
+// ParseTrashInfo returns the original path and deletion date from the contents of a trash info file.
+func ParseTrashInfo(data []byte) (originalPath string, deletionDate time.Time, err error) {
+	var (
+		inGroup bool
+		hasPath bool
+	)
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+
+		switch {
+		case line == "" || strings.HasPrefix(line, "#"):
+			continue
+		case strings.HasPrefix(line, "["):
+			inGroup = line == "[Trash Info]"
+
+			continue
+		case !inGroup:
+			continue
+		}
+
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			continue
+		}
+
+		switch strings.TrimSpace(key) {
+		case "Path":
+			originalPath, err = url.PathUnescape(strings.TrimSpace(value))
+			if err != nil {
+				return "", time.Time{}, fmt.Errorf("reading trash info: %w", err)
+			}
+
+			hasPath = originalPath != ""
+		case "DeletionDate":
+			// An invalid date is not fatal, the item can still be restored.
+			deletionDate, _ = time.ParseInLocation(trashInfoTimeFormat, strings.TrimSpace(value), time.Local)
+		}
+	}
+
+	if !hasPath {
+		return "", time.Time{}, errors.New("reading trash info: missing path")
+	}
+
+	return originalPath, deletionDate, nil
+}
+
+// RestoreTrash moves an item from the trash back to where it was. It fails
+// rather than replacing anything which has since been created there.
+func RestoreTrash(item TrashItem) error {
+	if _, err := os.Lstat(item.OriginalPath); err == nil {
+		return fmt.Errorf("can not restore %s: %w", item.OriginalPath, fs.ErrExist)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(item.OriginalPath), 0o755); err != nil {
+		return errors.Unwrap(err)
+	}
+
+	if err := os.Rename(item.Path(), item.OriginalPath); err != nil {
+		return errors.Unwrap(err)
+	}
+
+	return errors.Unwrap(os.Remove(item.infoPath()))
+}
+
+// RemoveFromTrash permanently deletes an item in the trash.
+func RemoveFromTrash(item TrashItem) error {
+	if err := os.RemoveAll(item.Path()); err != nil {
+		return errors.Unwrap(err)
+	}
+
+	return errors.Unwrap(os.Remove(item.infoPath()))
+}
+
+// EmptyTrash permanently deletes everything in every trash directory.
+func EmptyTrash() error {
+	for _, trashDirectory := range TrashDirectories() {
+		for _, dir := range []string{"files", "info"} {
+			entries, err := os.ReadDir(filepath.Join(trashDirectory, dir))
+			if errors.Is(err, fs.ErrNotExist) {
+				continue
+			}
+
+			if err != nil {
+				return errors.Unwrap(err)
+			}
+
+			for _, entry := range entries {
+				if err := os.RemoveAll(filepath.Join(trashDirectory, dir, entry.Name())); err != nil {
+					return errors.Unwrap(err)
+				}
+			}
+		}
+
+		// The cache of directory sizes only describes items which are now gone.
+		if err := os.Remove(filepath.Join(trashDirectory, "directorysizes")); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return errors.Unwrap(err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,11 @@
+//go:build !unix
+
+package filesystem
+
+import "io/fs"
+
+// deviceID returns the device containing a file, devices are not available
+// on this platform so everything is moved to the trash of the user.
+func deviceID(info fs.FileInfo) (uint64, bool) {
+	return 0, false
+}
@@ -0,0 +1,380 @@
+package filesystem
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// setupTrash points the trash of the user at a temporary directory.
+func setupTrash(t *testing.T) string {
+	t.Helper()
+
+	dataHome := t.TempDir()
+	t.Setenv("XDG_DATA_HOME", dataHome)
+
+	return filepath.Join(dataHome, "Trash")
+}
+
+// writeTestFile creates a file along with the directories containing it.
+func writeTestFile(t *testing.T, name, content string) {
+	t.Helper()
+
+	if err := os.MkdirAll(filepath.Dir(name), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.WriteFile(name, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// listHomeTrash returns the items in the trash of the user, leaving out
+// those in trash directories of other mounts on the machine running the tests.
+func listHomeTrash(t *testing.T, homeTrash string) []TrashItem {
+	t.Helper()
+
+	items, err := ListTrash()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var homeItems []TrashItem
+
+	for _, item := range items {
+		if item.TrashDirectory == homeTrash {
+			homeItems = append(homeItems, item)
+		}
+	}
+
+	return homeItems
+}
+
+func TestHomeTrashDirectory(t *testing.T) {
+	homeTrash := setupTrash(t)
+
+	got, err := HomeTrashDirectory()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got != homeTrash {
+		t.Errorf("HomeTrashDirectory() = %q, want %q", got, homeTrash)
+	}
+}
+
+func TestTrashLayout(t *testing.T) {
+	homeTrash := setupTrash(t)
+	name := filepath.Join(t.TempDir(), "notes.txt")
+	writeTestFile(t, name, "content")
+
+	before := time.Now().Truncate(time.Second)
+
+	item, err := trash(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if item.TrashDirectory != homeTrash {
+		t.Fatalf("TrashDirectory = %q, want %q", item.TrashDirectory, homeTrash)
+	}
+
+	if _, err := os.Lstat(name); !os.IsNotExist(err) {
+		t.Errorf("the item is still at %s", name)
+	}
+
+	for _, dir := range []string{"files", "info"} {
+		info, err := os.Stat(filepath.Join(homeTrash, dir))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if perm := info.Mode().Perm(); perm != 0o700 {
+			t.Errorf("%s has permissions %o, want 700", dir, perm)
+		}
+	}
+
+	data, err := os.ReadFile(filepath.Join(homeTrash, "files", "notes.txt"))
+	if err != nil || string(data) != "content" {
+		t.Errorf("trashed file = %q, %v, want %q", data, err, "content")
+	}
+
+	info, err := os.ReadFile(filepath.Join(homeTrash, "info", "notes.txt.trashinfo"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(string(info)), "\n")
+	if len(lines) != 3 || lines[0] != "[Trash Info]" || lines[1] != "Path="+filepath.ToSlash(name) {
+		t.Errorf("unexpected trash info:\n%s", info)
+	}
+
+	originalPath, deletionDate, err := ParseTrashInfo(info)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if originalPath != name {
+		t.Errorf("original path = %q, want %q", originalPath, name)
+	}
+
+	if deletionDate.Before(before) || deletionDate.After(time.Now()) {
+		t.Errorf("deletion date %v is not the time the item was trashed", deletionDate)
+	}
+
+	items := listHomeTrash(t, homeTrash)
+
+	if len(items) != 1 || items[0].Name != "notes.txt" || items[0].OriginalPath != name {
+		t.Fatalf("ListTrash() = %+v, want the trashed item", items)
+	}
+
+	if err := RestoreTrash(items[0]); err != nil {
+		t.Fatal(err)
+	}
+
+	if data, err := os.ReadFile(name); err != nil || string(data) != "content" {
+		t.Errorf("restored file = %q, %v, want %q", data, err, "content")
+	}
+
+	if _, err := os.Lstat(item.infoPath()); !os.IsNotExist(err) {
+		t.Errorf("the trash info file was not removed when restoring")
+	}
+}
+
+func TestTrashEscapesPath(t *testing.T) {
+	setupTrash(t)
+
+	name := filepath.Join(t.TempDir(), "my notes%20#1?.txt")
+	writeTestFile(t, name, "")
+
+	item, err := trash(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	info, err := os.ReadFile(item.infoPath())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.Contains(string(info), "my%20notes%2520%231%3F.txt\n") {
+		t.Errorf("the path in the trash info is not escaped:\n%s", info)
+	}
+
+	originalPath, _, err := ParseTrashInfo(info)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if originalPath != name {
+		t.Errorf("original path = %q, want %q", originalPath, name)
+	}
+}
+
+func TestTrashNameCollisions(t *testing.T) {
+	homeTrash := setupTrash(t)
+	dir := t.TempDir()
+
+	// A name left in the files directory without an info file is not reused.
+	writeTestFile(t, filepath.Join(homeTrash, "files", "report.3.txt"), "stray")
+
+	var names []string
+
+	for _, sub := range []string{"a", "b", "c", "d"} {
+		name := filepath.Join(dir, sub, "report.txt")
+		writeTestFile(t, name, sub)
+
+		item, err := trash(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		names = append(names, item.Name)
+
+		data, err := os.ReadFile(item.Path())
+		if err != nil || string(data) != sub {
+			t.Errorf("%s = %q, %v, want %q", item.Name, data, err, sub)
+		}
+	}
+
+	want := []string{"report.txt", "report.2.txt", "report.4.txt", "report.5.txt"}
+	if strings.Join(names, ",") != strings.Join(want, ",") {
+		t.Errorf("trashed names = %v, want %v", names, want)
+	}
+
+	items := listHomeTrash(t, homeTrash)
+
+	if len(items) != len(want) {
+		t.Errorf("ListTrash() = %d items, want %d", len(items), len(want))
+	}
+}
+
+func TestTrashRelativePaths(t *testing.T) {
+	setupTrash(t)
+
+	tests := []struct {
+		name           string
+		trashDirectory func(top string) string
+	}{
+		{"user trash directory", func(top string) string { return filepath.Join(top, ".Trash-1000") }},
+		{"shared trash directory", func(top string) string { return filepath.Join(top, ".Trash", "1000") }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			top := t.TempDir()
+			trashDirectory := tt.trashDirectory(top)
+			name := filepath.Join(top, "projects", "my file.txt")
+			writeTestFile(t, name, "content")
+
+			if err := makeTrashDirectory(trashDirectory); err != nil {
+				t.Fatal(err)
+			}
+
+			item, err := moveToTrash(name, filepath.Join("projects", "my file.txt"), trashDirectory)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			info, err := os.ReadFile(item.infoPath())
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !strings.Contains(string(info), "\nPath=projects/my%20file.txt\n") {
+				t.Errorf("the path in the trash info is not relative to the top directory:\n%s", info)
+			}
+
+			items, err := listTrashDirectory(trashDirectory)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(items) != 1 || items[0].OriginalPath != name {
+				t.Fatalf("listTrashDirectory() = %+v, want an item from %s", items, name)
+			}
+
+			if err := RestoreTrash(items[0]); err != nil {
+				t.Fatal(err)
+			}
+
+			if _, err := os.Stat(name); err != nil {
+				t.Errorf("the item was not restored: %v", err)
+			}
+		})
+	}
+}
+
+func TestListTrashSkipsInvalidItems(t *testing.T) {
+	homeTrash := setupTrash(t)
+
+	writeTestFile(t, filepath.Join(homeTrash, "files", "kept"), "")
+	writeTestFile(t, filepath.Join(homeTrash, "info", "kept.trashinfo"),
+		"[Trash Info]\nPath=/tmp/kept\nDeletionDate=2024-01-02T03:04:05\n")
+
+	// The item described by this info file is gone.
+	writeTestFile(t, filepath.Join(homeTrash, "info", "gone.trashinfo"),
+		"[Trash Info]\nPath=/tmp/gone\nDeletionDate=2024-01-02T03:04:05\n")
+
+	// This info file has no path.
+	writeTestFile(t, filepath.Join(homeTrash, "files", "broken"), "")
+	writeTestFile(t, filepath.Join(homeTrash, "info", "broken.trashinfo"), "[Trash Info]\n")
+
+	items := listHomeTrash(t, homeTrash)
+
+	if len(items) != 1 || items[0].Name != "kept" || items[0].OriginalPath != "/tmp/kept" {
+		t.Errorf("ListTrash() = %+v, want only the kept item", items)
+	}
+}
+
+func TestParseTrashInfo(t *testing.T) {
+	date := time.Date(2024, 1, 2, 3, 4, 5, 0, time.Local)
+
+	tests := []struct {
+		name     string
+		data     string
+		wantPath string
+		wantDate time.Time
+		wantErr  bool
+	}{
+		{
+			name:     "valid",
+			data:     "[Trash Info]\nPath=/home/user/file.txt\nDeletionDate=2024-01-02T03:04:05\n",
+			wantPath: "/home/user/file.txt",
+			wantDate: date,
+		},
+		{
+			name:     "escaped path",
+			data:     "[Trash Info]\nPath=/home/user/my%20file%25.txt\nDeletionDate=2024-01-02T03:04:05\n",
+			wantPath: "/home/user/my file%.txt",
+			wantDate: date,
+		},
+		{
+			name:     "relative path",
+			data:     "[Trash Info]\nPath=dir/file.txt\nDeletionDate=2024-01-02T03:04:05\n",
+			wantPath: "dir/file.txt",
+			wantDate: date,
+		},
+		{
+			name:     "comments, blank lines and spaces",
+			data:     "# comment\n\n[Trash Info]\n  Path = /file \r\nDeletionDate=2024-01-02T03:04:05\r\n",
+			wantPath: "/file",
+			wantDate: date,
+		},
+		{
+			name:     "keys outside of the group are ignored",
+			data:     "Path=/outside\n[Other]\nPath=/other\n[Trash Info]\nPath=/inside\n",
+			wantPath: "/inside",
+		},
+		{
+			name:     "invalid date",
+			data:     "[Trash Info]\nPath=/file\nDeletionDate=yesterday\n",
+			wantPath: "/file",
+		},
+		{
+			name:    "missing path",
+			data:    "[Trash Info]\nDeletionDate=2024-01-02T03:04:05\n",
+			wantErr: true,
+		},
+		{
+			name:    "missing group",
+			data:    "Path=/file\n",
+			wantErr: true,
+		},
+		{
+			name:    "invalid escape",
+			data:    "[Trash Info]\nPath=/file%zz\n",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path, date, err := ParseTrashInfo([]byte(tt.data))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseTrashInfo() error = %v, want error %v", err, tt.wantErr)
+			}
+
+			if path != tt.wantPath || !date.Equal(tt.wantDate) {
+				t.Errorf("ParseTrashInfo() = %q, %v, want %q, %v", path, date, tt.wantPath, tt.wantDate)
+			}
+		})
+	}
+}
+
+func TestUnescapeMountPath(t *testing.T) {
+	tests := map[string]string{
+		"/mnt/usb":              "/mnt/usb",
+		`/mnt/my\040disk`:       "/mnt/my disk",
+		`/mnt/tab\011and\134bs`: "/mnt/tab\tand\\bs",
+		`/mnt/trailing\04`:      `/mnt/trailing\04`,
+	}
+
+	for path, want := range tests {
+		if got := unescapeMountPath(path); got != want {
+			t.Errorf("unescapeMountPath(%q) = %q, want %q", path, got, want)
+		}
+	}
+}
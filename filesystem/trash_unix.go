@@ -0,0 +1,18 @@
+//go:build unix
+
+package filesystem
+
+import (
+	"io/fs"
+	"syscall"
+)
+
+// deviceID returns the device containing a file.
+func deviceID(info fs.FileInfo) (uint64, bool) {
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return 0, false
+	}
+
+	return uint64(stat.Dev), true //nolint:unconvert // Dev is not a uint64 on every platform.
+}
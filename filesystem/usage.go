@@ -0,0 +1,396 @@
+package filesystem
+
+import (
+	"cmp"
+	"errors"
+	"io/fs"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"sync"
+	"sync/atomic"
+)
+
+// UsageOptions configures a disk usage scan.
+type UsageOptions struct {
+	// Workers is the number of directories read at the same time,
+	// the number of CPUs is used when it is zero or less.
+	Workers int
+	// OneFileSystem skips directories on other filesystems than the
+	// scanned directory, such as mount points.
+	OneFileSystem bool
+	// ApparentSize counts the size of the content of files instead of
+	// the space allocated for them on disk.
+	ApparentSize bool
+}
+
+// UsageEntry is the disk usage of a directory or file.
+type UsageEntry struct {
+	Name  string
+	Path  string
+	IsDir bool
+	// Size is the total size of the files below a directory, files with
+	// several hard links are only counted for the first one found. It is the
+	// space allocated on disk where it is known, unless the apparent size is asked for.
+	Size int64
+	// Items is the number of directories and files below a directory.
+	Items int64
+	// Complete reports if every directory below a directory has been read.
+	Complete bool
+	// OtherFileSystem reports if a directory was skipped as it is on another filesystem.
+	OtherFileSystem bool
+	// Err is the first error reading a directory, its size is missing what could not be read.
+	Err error
+}
+
+// usageNode is a directory or file found by a scan.
+type usageNode struct {
+	name            string
+	parent          *usageNode
+	children        []*usageNode
+	isDir           bool
+	size            int64
+	items           int64
+	pending         int
+	otherFileSystem bool
+	err             error
+}
+
+// path returns the path of a node below the root of a scan.
+func (n *usageNode) path(root string) string {
+	if n.parent == nil {
+		return root
+	}
+
+	return filepath.Join(n.parent.path(root), n.name)
+}
+
+// entry returns a copy of a node which can be read while the scan goes on.
+func (n *usageNode) entry(root string) UsageEntry {
+	return UsageEntry{
+		Name:            n.name,
+		Path:            n.path(root),
+		IsDir:           n.isDir,
+		Size:            n.size,
+		Items:           n.items,
+		Complete:        n.pending == 0,
+		OtherFileSystem: n.otherFileSystem,
+		Err:             n.err,
+	}
+}
+
+// hardLinkKey identifies a file with several hard links.
+type hardLinkKey struct {
+	device uint64
+	inode  uint64
+}
+
+// UsageScan reads the disk usage of a directory in the background. Its
+// results can be read while it is running, they grow as directories are read.
+type UsageScan struct {
+	fsys      FS
+	root      string
+	options   UsageOptions
+	device    uint64
+	hasDevice bool
+
+	mu          sync.Mutex
+	cond        *sync.Cond
+	rootNode    *usageNode
+	directories map[string]*usageNode
+	queue       []*usageNode
+	active      int
+	hardLinks   map[hardLinkKey]struct{}
+	errors      int
+
+	cancelled atomic.Bool
+	done      chan struct{}
+}
+
+// ScanUsage starts reading the disk usage of a directory or file.
+func ScanUsage(name string, options UsageOptions) *UsageScan {
+	return ScanUsageFS(OSFS{}, name, options)
+}
+
+// ScanUsageFS starts reading the disk usage of a directory or file.
+func ScanUsageFS(fsys FS, name string, options UsageOptions) *UsageScan {
+	name = filepath.Clean(name)
+
+	s := &UsageScan{
+		fsys:        fsys,
+		root:        name,
+		options:     options,
+		rootNode:    &usageNode{name: filepath.Base(name), pending: 1},
+		directories: make(map[string]*usageNode),
+		hardLinks:   make(map[hardLinkKey]struct{}),
+		done:        make(chan struct{}),
+	}
+
+	s.cond = sync.NewCond(&s.mu)
+
+	if s.options.Workers <= 0 {
+		s.options.Workers = runtime.NumCPU()
+	}
+
+	go s.run()
+
+	return s
+}
+
+// run reads the scanned directory with a fixed number of workers.
+func (s *UsageScan) run() {
+	defer close(s.done)
+
+	info, err := s.fsys.Stat(s.root)
+	if err != nil {
+		s.mu.Lock()
+		s.rootNode.err = errors.Unwrap(err)
+		s.rootNode.pending = 0
+		s.errors++
+		s.mu.Unlock()
+
+		return
+	}
+
+	s.device, s.hasDevice = deviceID(info)
+
+	s.mu.Lock()
+	if info.IsDir() {
+		s.rootNode.isDir = true
+		s.directories[s.root] = s.rootNode
+		s.queue = append(s.queue, s.rootNode)
+	} else {
+		s.rootNode.size = s.fileSize(info)
+		s.rootNode.pending = 0
+	}
+	s.mu.Unlock()
+
+	var wg sync.WaitGroup
+
+	for range s.options.Workers {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			for node := s.next(); node != nil; node = s.next() {
+				s.readDirectory(node)
+			}
+		}()
+	}
+
+	wg.Wait()
+}
+
+// next waits for a directory to read, it returns nil once there are none left.
+func (s *UsageScan) next() *usageNode {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for len(s.queue) == 0 && s.active > 0 && !s.cancelled.Load() {
+		s.cond.Wait()
+	}
+
+	if len(s.queue) == 0 || s.cancelled.Load() {
+		s.cond.Broadcast()
+
+		return nil
+	}
+
+	// Directories are read depth first which keeps the queue short.
+	node := s.queue[len(s.queue)-1]
+	s.queue = s.queue[:len(s.queue)-1]
+	s.active++
+
+	return node
+}
+
+// fileSize returns the size of a file, which is zero for the
+// hard links of a file after the first one. It is called with s.mu held.
+func (s *UsageScan) fileSize(info fs.FileInfo) int64 {
+	if device, inode, ok := hardLinkID(info); ok {
+		key := hardLinkKey{device: device, inode: inode}
+		if _, seen := s.hardLinks[key]; seen {
+			return 0
+		}
+
+		s.hardLinks[key] = struct{}{}
+	}
+
+	if s.options.ApparentSize {
+		return info.Size()
+	}
+
+	return allocatedSize(info)
+}
+
+// readDirectory reads the entries of a directory, queueing its subdirectories
+// and adding the size of its files to it and every directory above it.
+func (s *UsageScan) readDirectory(node *usageNode) {
+	path := node.path(s.root)
+	entries, readErr := s.fsys.ReadDir(path)
+
+	type child struct {
+		node *usageNode
+		info fs.FileInfo
+	}
+
+	// Files are read before taking the lock so that workers are not blocked on it.
+	children := make([]child, 0, len(entries))
+
+	for _, entry := range entries {
+		info, err := entry.Info()
+		if err != nil {
+			// The entry was removed since the directory was read.
+			continue
+		}
+
+		childNode := &usageNode{name: entry.Name(), parent: node, isDir: entry.IsDir()}
+
+		if childNode.isDir && s.options.OneFileSystem && s.hasDevice {
+			if device, ok := deviceID(info); ok && device != s.device {
+				childNode.otherFileSystem = true
+			}
+		}
+
+		children = append(children, child{node: childNode, info: info})
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if readErr != nil {
+		node.err = errors.Unwrap(readErr)
+		s.errors++
+	}
+
+	var size int64
+
+	for _, child := range children {
+		node.children = append(node.children, child.node)
+
+		switch {
+		case child.node.otherFileSystem:
+			// Directories on other filesystems are listed without being read.
+		case child.node.isDir:
+			child.node.pending = 1
+			s.directories[child.node.path(s.root)] = child.node
+			s.queue = append(s.queue, child.node)
+			node.pending++
+		default:
+			child.node.size = s.fileSize(child.info)
+			size += child.node.size
+		}
+	}
+
+	for parent := node; parent != nil; parent = parent.parent {
+		parent.size += size
+		parent.items += int64(len(children))
+	}
+
+	s.active--
+	s.finish(node)
+	s.cond.Broadcast()
+}
+
+// finish marks a directory as read, directories above it are complete once
+// every directory below them has been read. It is called with s.mu held.
+func (s *UsageScan) finish(node *usageNode) {
+	for ; node != nil; node = node.parent {
+		node.pending--
+		if node.pending > 0 {
+			return
+		}
+	}
+}
+
+// Root returns the scanned directory or file.
+func (s *UsageScan) Root() string {
+	return s.root
+}
+
+// Entry returns the disk usage of a directory found by the scan so far,
+// or of the scanned file.
+func (s *UsageScan) Entry(name string) (UsageEntry, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	name = filepath.Clean(name)
+	if name == s.root {
+		return s.rootNode.entry(s.root), true
+	}
+
+	node, ok := s.directories[name]
+	if !ok {
+		return UsageEntry{}, false
+	}
+
+	return node.entry(s.root), true
+}
+
+// Entries returns the disk usage of the entries of a directory found by
+// the scan so far, largest first.
+func (s *UsageScan) Entries(name string) ([]UsageEntry, bool) {
+	s.mu.Lock()
+
+	node, ok := s.directories[filepath.Clean(name)]
+	if !ok {
+		s.mu.Unlock()
+
+		return nil, false
+	}
+
+	entries := make([]UsageEntry, len(node.children))
+	for i, child := range node.children {
+		entries[i] = child.entry(s.root)
+	}
+
+	s.mu.Unlock()
+
+	slices.SortFunc(entries, func(a, b UsageEntry) int {
+		if c := cmp.Compare(b.Size, a.Size); c != 0 {
+			return c
+		}
+
+		return cmp.Compare(a.Name, b.Name)
+	})
+
+	return entries, true
+}
+
+// Errors returns the number of directories which could not be read.
+func (s *UsageScan) Errors() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.errors
+}
+
+// Cancel stops the scan, keeping what was read so far.
+func (s *UsageScan) Cancel() {
+	s.cancelled.Store(true)
+
+	s.mu.Lock()
+	s.cond.Broadcast()
+	s.mu.Unlock()
+}
+
+// Done returns a channel which is closed once the scan has finished or was cancelled.
+func (s *UsageScan) Done() <-chan struct{} {
+	return s.done
+}
+
+// IsDone reports if the scan has finished or was cancelled.
+func (s *UsageScan) IsDone() bool {
+	select {
+	case <-s.done:
+		return true
+	default:
+		return false
+	}
+}
+
+// Wait blocks until the scan has finished or was cancelled.
+func (s *UsageScan) Wait() {
+	<-s.done
+}
@@ -0,0 +1,17 @@
+//go:build !unix
+
+package filesystem
+
+import "io/fs"
+
+// hardLinkID returns the device and inode of a file which has several hard
+// links, they are not available on this platform so every file is counted.
+func hardLinkID(info fs.FileInfo) (device, inode uint64, ok bool) {
+	return 0, 0, false
+}
+
+// allocatedSize returns the space allocated for a file on disk, it is not
+// available on this platform so the size of the file is used.
+func allocatedSize(info fs.FileInfo) int64 {
+	return info.Size()
+}
@@ -0,0 +1,139 @@
+package filesystem
+
+import (
+	"io/fs"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestScanUsage(t *testing.T) {
+	fsys := NewMemFS()
+	writeMemFile(t, fsys, "/root/small.txt", "12")
+	writeMemFile(t, fsys, "/root/big/a.bin", "1234567890")
+	writeMemFile(t, fsys, "/root/big/deep/b.bin", "12345")
+	writeMemFile(t, fsys, "/root/medium/c.bin", "1234")
+
+	scan := ScanUsageFS(fsys, "/root", UsageOptions{Workers: 2})
+	scan.Wait()
+
+	total, ok := scan.Entry("/root")
+	if !ok {
+		t.Fatal("Entry() found no root")
+	}
+
+	if total.Size != 21 || total.Items != 7 || !total.Complete {
+		t.Errorf("Entry(root) = %+v, want 21 bytes in 7 complete items", total)
+	}
+
+	entries, ok := scan.Entries("/root")
+	if !ok {
+		t.Fatal("Entries() found no root")
+	}
+
+	var names []string
+	for _, entry := range entries {
+		names = append(names, entry.Name)
+	}
+
+	if want := []string{"big", "medium", "small.txt"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("Entries() = %v, want %v, largest first", names, want)
+	}
+
+	big, ok := scan.Entry("/root/big")
+	if !ok || big.Size != 15 || big.Items != 3 {
+		t.Errorf("Entry(big) = %+v, %v, want 15 bytes in 3 items", big, ok)
+	}
+
+	if _, ok := scan.Entries("/root/small.txt"); ok {
+		t.Error("Entries() of a file found entries")
+	}
+}
+
+func TestScanUsageFile(t *testing.T) {
+	fsys := NewMemFS()
+	writeMemFile(t, fsys, "/root/file.txt", "123")
+
+	scan := ScanUsageFS(fsys, "/root/file.txt", UsageOptions{})
+	scan.Wait()
+
+	entry, ok := scan.Entry("/root/file.txt")
+	if !ok || entry.Size != 3 || entry.IsDir || !entry.Complete {
+		t.Errorf("Entry() = %+v, %v, want a complete file of 3 bytes", entry, ok)
+	}
+}
+
+func TestScanUsageMissing(t *testing.T) {
+	scan := ScanUsageFS(NewMemFS(), "/missing", UsageOptions{})
+	scan.Wait()
+
+	entry, _ := scan.Entry("/missing")
+	if entry.Err == nil || scan.Errors() != 1 {
+		t.Errorf("Entry() = %+v with %d errors, want an error", entry, scan.Errors())
+	}
+}
+
+// blockingFS is a MemFS which blocks reading directories other than the root
+// until released, telling the test once a read is blocked.
+type blockingFS struct {
+	*MemFS
+	root    string
+	blocked chan struct{}
+	release chan struct{}
+}
+
+func (f blockingFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	if name != f.root {
+		f.blocked <- struct{}{}
+		<-f.release
+	}
+
+	return f.MemFS.ReadDir(name)
+}
+
+func TestScanUsageCancel(t *testing.T) {
+	fsys := blockingFS{
+		MemFS:   NewMemFS(),
+		root:    "/root",
+		blocked: make(chan struct{}, 3),
+		release: make(chan struct{}),
+	}
+
+	for _, name := range []string{"/root/a/file", "/root/b/file", "/root/c/file"} {
+		writeMemFile(t, fsys.MemFS, name, "1234")
+	}
+
+	scan := ScanUsageFS(fsys, "/root", UsageOptions{Workers: 1})
+
+	<-fsys.blocked
+	scan.Cancel()
+	close(fsys.release)
+
+	select {
+	case <-scan.Done():
+	case <-time.After(5 * time.Second):
+		t.Fatal("the scan did not stop once cancelled")
+	}
+
+	if !scan.IsDone() {
+		t.Error("IsDone() = false after the scan stopped")
+	}
+
+	total, _ := scan.Entry("/root")
+	if total.Complete {
+		t.Errorf("Entry(root) = %+v, want it to be incomplete", total)
+	}
+
+	// The directory being read when the scan was cancelled is kept, the others are never read.
+	read := 0
+
+	for _, name := range []string{"/root/a", "/root/b", "/root/c"} {
+		if entry, ok := scan.Entry(name); ok && entry.Complete {
+			read++
+		}
+	}
+
+	if read != 1 || total.Size != 4 {
+		t.Errorf("%d directories and %d bytes were read, want 1 and 4", read, total.Size)
+	}
+}
@@ -0,0 +1,30 @@
+//go:build unix
+
+package filesystem
+
+import (
+	"io/fs"
+	"syscall"
+)
+
+// hardLinkID returns the device and inode of a file which has several hard links.
+func hardLinkID(info fs.FileInfo) (device, inode uint64, ok bool) {
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok || info.IsDir() || stat.Nlink < 2 {
+		return 0, 0, false
+	}
+
+	return uint64(stat.Dev), uint64(stat.Ino), true //nolint:unconvert // Dev and Ino are not uint64 on every platform.
+}
+
+// allocatedSize returns the space allocated for a file on disk, which is
+// its size for backends which do not report it.
+func allocatedSize(info fs.FileInfo) int64 {
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return info.Size()
+	}
+
+	// Blocks are always counted in units of 512 bytes.
+	return int64(stat.Blocks) * 512 //nolint:unconvert // Blocks is not int64 on every platform.
+}
@@ -0,0 +1,123 @@
+//go:build unix
+
+package filesystem
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+)
+
+func TestScanUsageHardLinks(t *testing.T) {
+	dir := t.TempDir()
+	name := filepath.Join(dir, "a", "file")
+	writeTestFile(t, name, "1234567890")
+
+	if err := os.MkdirAll(filepath.Join(dir, "b"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, link := range []string{filepath.Join(dir, "a", "link"), filepath.Join(dir, "b", "link")} {
+		if err := os.Link(name, link); err != nil {
+			t.Skipf("hard links are not supported: %v", err)
+		}
+	}
+
+	scan := ScanUsage(dir, UsageOptions{ApparentSize: true})
+	scan.Wait()
+
+	total, _ := scan.Entry(dir)
+	if total.Size != 10 || total.Items != 5 {
+		t.Errorf("Entry() = %+v, want 10 bytes in 5 items", total)
+	}
+}
+
+// deviceFS is a MemFS which reports the directories below a path as being on another device.
+type deviceFS struct {
+	*MemFS
+	mount string
+}
+
+// statInfo is a FileInfo which returns a Stat_t from Sys.
+type statInfo struct {
+	fs.FileInfo
+	stat *syscall.Stat_t
+}
+
+func (i statInfo) Sys() any {
+	return i.stat
+}
+
+func (f deviceFS) info(name string, info fs.FileInfo) fs.FileInfo {
+	stat := &syscall.Stat_t{Dev: 1}
+	if name == f.mount || filepath.Dir(name) == f.mount {
+		stat.Dev = 2
+	}
+
+	return statInfo{FileInfo: info, stat: stat}
+}
+
+func (f deviceFS) Stat(name string) (fs.FileInfo, error) {
+	info, err := f.MemFS.Stat(name)
+	if err != nil {
+		return nil, err
+	}
+
+	return f.info(name, info), nil
+}
+
+func (f deviceFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	entries, err := f.MemFS.ReadDir(name)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, entry := range entries {
+		info, err := entry.Info()
+		if err != nil {
+			return nil, err
+		}
+
+		entries[i] = fs.FileInfoToDirEntry(f.info(filepath.Join(name, entry.Name()), info))
+	}
+
+	return entries, nil
+}
+
+func TestScanUsageOneFileSystem(t *testing.T) {
+	fsys := deviceFS{MemFS: NewMemFS(), mount: "/root/mnt"}
+	writeMemFile(t, fsys.MemFS, "/root/local/file", "1234")
+	writeMemFile(t, fsys.MemFS, "/root/mnt/file", "1234567890")
+
+	tests := []struct {
+		oneFileSystem bool
+		wantSize      int64
+	}{
+		{false, 14},
+		{true, 4},
+	}
+
+	for _, tt := range tests {
+		scan := ScanUsageFS(fsys, "/root", UsageOptions{OneFileSystem: tt.oneFileSystem, ApparentSize: true})
+		scan.Wait()
+
+		total, _ := scan.Entry("/root")
+		if total.Size != tt.wantSize || !total.Complete {
+			t.Errorf("OneFileSystem %v: Entry() = %+v, want %d bytes", tt.oneFileSystem, total, tt.wantSize)
+		}
+
+		// Directories on other filesystems are listed without being read.
+		if _, ok := scan.Entry("/root/mnt"); ok == tt.oneFileSystem {
+			t.Errorf("OneFileSystem %v: Entry(mnt) found = %v", tt.oneFileSystem, ok)
+		}
+
+		entries, _ := scan.Entries("/root")
+		for _, entry := range entries {
+			if entry.Name == "mnt" && entry.OtherFileSystem != tt.oneFileSystem {
+				t.Errorf("OneFileSystem %v: OtherFileSystem = %v", tt.oneFileSystem, entry.OtherFileSystem)
+			}
+		}
+	}
+}
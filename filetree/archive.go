@@ -0,0 +1,110 @@
+package filetree
+
+import (
+	"io"
+	"path/filepath"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/mistakenelf/teacup/filesystem"
+)
+
+// archive is an archive being browsed along with the backend containing it.
+type archive struct {
+	fsys   filesystem.FS
+	closer io.Closer
+	path   string
+}
+
+type archiveOpenedMsg struct {
+	archive archive
+	fsys    filesystem.FS
+}
+
+// openArchiveCmd opens an archive so that it can be browsed like a directory.
+func openArchiveCmd(fsys filesystem.FS, path string) tea.Cmd {
+	return func() tea.Msg {
+		archiveFS, closer, err := filesystem.OpenArchive(fsys, path)
+		if err != nil {
+			return errorMsg(err)
+		}
+
+		return archiveOpenedMsg{
+			archive: archive{fsys: fsys, closer: closer, path: path},
+			fsys:    archiveFS,
+		}
+	}
+}
+
+// ArchivePath returns the path of the archive being browsed, or an
+// empty string when browsing a directory.
+func (m Model) ArchivePath() string {
+	if len(m.archives) == 0 {
+		return ""
+	}
+
+	return m.archives[len(m.archives)-1].path
+}
+
+// switchFileSystem browses another backend without closing open archives.
+func (m *Model) switchFileSystem(fsys filesystem.FS) {
+	m.stopWatching()
+	m.fsys = fsys
+	m.currentDirectory = ""
+	m.gitStatuses = nil
+	clear(m.expanded)
+	m.ClearSelection()
+}
+
+// enterArchive starts browsing an archive which was opened.
+func (m *Model) enterArchive(msg archiveOpenedMsg) tea.Cmd {
+	m.pushHistory()
+	m.archives = append(m.archives, msg.archive)
+	m.switchFileSystem(msg.fsys)
+
+	return m.getDirectoryListingCmd(filesystem.RootDirectory)
+}
+
+// leaveArchive closes the current archive, returning to the directory containing it.
+func (m *Model) leaveArchive() tea.Cmd {
+	current := m.archives[len(m.archives)-1]
+	m.archives = m.archives[:len(m.archives)-1]
+	_ = current.closer.Close()
+
+	m.switchFileSystem(current.fsys)
+	m.returnPath = current.path
+
+	return m.getDirectoryListingCmd(filepath.Dir(current.path))
+}
+
+// closeArchives closes every archive being browsed, returning to
+// the backend containing the outermost archive.
+func (m *Model) closeArchives() {
+	if len(m.archives) == 0 {
+		return
+	}
+
+	fsys := m.archives[0].fsys
+	for i := len(m.archives) - 1; i >= 0; i-- {
+		_ = m.archives[i].closer.Close()
+	}
+
+	m.archives = nil
+	m.switchFileSystem(fsys)
+}
+
+// extractCmd extracts the target items of the current archive into the
+// directory containing the archive, recording it in the journal.
+func (m Model) extractCmd() tea.Cmd {
+	current := m.archives[len(m.archives)-1]
+	fsys := m.fsys
+	items := m.targetItems()
+
+	paths := make([]string, 0, len(items))
+	for _, item := range items {
+		paths = append(paths, item.path)
+	}
+
+	return m.journaledCmdFS(current.fsys, func(batch *filesystem.Batch) error {
+		return batch.Extract(fsys, paths, filepath.Dir(current.path))
+	}, m.files[m.cursor].path)
+}
@@ -0,0 +1,297 @@
+package filetree
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/mistakenelf/teacup/filesystem"
+)
+
+// Bookmark is a directory saved under a single letter mark.
+type Bookmark struct {
+	Mark      rune
+	Directory string
+}
+
+// Bookmarks is a store of bookmarks which is saved to a file whenever it
+// changes. A single store can be shared by several models.
+type Bookmarks struct {
+	mu     sync.RWMutex
+	path   string
+	marks  map[rune]string
+	loaded bool
+}
+
+// DefaultBookmarksPath returns the file bookmarks are saved to, which is
+// in $XDG_STATE_HOME or ~/.local/state when it is not set.
+func DefaultBookmarksPath() (string, error) {
+	stateHome := os.Getenv("XDG_STATE_HOME")
+	if stateHome == "" {
+		home, err := filesystem.GetHomeDirectory()
+		if err != nil {
+			return "", err
+		}
+
+		stateHome = filepath.Join(home, ".local", "state")
+	}
+
+	return filepath.Join(stateHome, "teacup", "bookmarks.json"), nil
+}
+
+// NewBookmarks returns an empty store saved to path. Bookmarks
+// are only kept in memory when path is empty.
+func NewBookmarks(path string) *Bookmarks {
+	return &Bookmarks{
+		path:  path,
+		marks: make(map[rune]string),
+	}
+}
+
+// LoadBookmarks returns a store with the bookmarks saved to path.
+func LoadBookmarks(path string) (*Bookmarks, error) {
+	bookmarks := NewBookmarks(path)
+
+	return bookmarks, bookmarks.Load()
+}
+
+// Path returns the file the bookmarks are saved to.
+func (b *Bookmarks) Path() string {
+	return b.path
+}
+
+// Load replaces the bookmarks with the ones saved to the file.
+// A missing file is treated as having no bookmarks.
+func (b *Bookmarks) Load() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.load()
+}
+
+// load reads the bookmarks from the file.
+func (b *Bookmarks) load() error {
+	b.loaded = true
+
+	if b.path == "" {
+		return nil
+	}
+
+	data, err := os.ReadFile(b.path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+
+	if err != nil {
+		return errors.Unwrap(err)
+	}
+
+	var saved map[string]string
+	if err := json.Unmarshal(data, &saved); err != nil {
+		return fmt.Errorf("reading bookmarks: %w", err)
+	}
+
+	clear(b.marks)
+
+	for mark, directory := range saved {
+		if r, size := utf8.DecodeRuneInString(mark); size == len(mark) && isBookmarkMark(r) {
+			b.marks[r] = directory
+		}
+	}
+
+	return nil
+}
+
+// Save writes the bookmarks to the file.
+func (b *Bookmarks) Save() error {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	return b.save()
+}
+
+// save writes the bookmarks to a temporary file which then replaces
+// the file, so that a failed write does not lose existing bookmarks.
+func (b *Bookmarks) save() error {
+	if b.path == "" {
+		return nil
+	}
+
+	saved := make(map[string]string, len(b.marks))
+	for mark, directory := range b.marks {
+		saved[string(mark)] = directory
+	}
+
+	data, err := json.MarshalIndent(saved, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(b.path), 0o755); err != nil {
+		return errors.Unwrap(err)
+	}
+
+	tempPath := b.path + ".tmp"
+	if err := os.WriteFile(tempPath, append(data, '\n'), 0o644); err != nil {
+		return errors.Unwrap(err)
+	}
+
+	return errors.Unwrap(os.Rename(tempPath, b.path))
+}
+
+// IsLoaded reports if the bookmarks have been loaded from the file.
+func (b *Bookmarks) IsLoaded() bool {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	return b.loaded
+}
+
+// Set saves a directory under a mark, replacing any directory already saved under it.
+func (b *Bookmarks) Set(mark rune, directory string) error {
+	if !isBookmarkMark(mark) {
+		return fmt.Errorf("invalid bookmark %q, marks must be a letter", mark)
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	// Load the file first so that saving does not lose bookmarks which were never read.
+	if !b.loaded {
+		if err := b.load(); err != nil {
+			return err
+		}
+	}
+
+	b.marks[mark] = directory
+
+	return b.save()
+}
+
+// Delete removes the directory saved under a mark.
+func (b *Bookmarks) Delete(mark rune) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	// Load the file first so that saving does not lose bookmarks which were never read.
+	if !b.loaded {
+		if err := b.load(); err != nil {
+			return err
+		}
+	}
+
+	delete(b.marks, mark)
+
+	return b.save()
+}
+
+// Get returns the directory saved under a mark.
+func (b *Bookmarks) Get(mark rune) (string, bool) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	directory, ok := b.marks[mark]
+
+	return directory, ok
+}
+
+// All returns every bookmark sorted by mark.
+func (b *Bookmarks) All() []Bookmark {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	bookmarks := make([]Bookmark, 0, len(b.marks))
+	for mark, directory := range b.marks {
+		bookmarks = append(bookmarks, Bookmark{Mark: mark, Directory: directory})
+	}
+
+	slices.SortFunc(bookmarks, func(a, b Bookmark) int {
+		return int(a.Mark - b.Mark)
+	})
+
+	return bookmarks
+}
+
+// isBookmarkMark reports if a rune can be used as a mark.
+func isBookmarkMark(mark rune) bool {
+	return unicode.IsLetter(mark)
+}
+
+type bookmarksLoadedMsg struct {
+	err error
+}
+
+type bookmarkSavedMsg struct {
+	err error
+}
+
+// loadBookmarksCmd loads the bookmarks unless they were already loaded.
+func loadBookmarksCmd(bookmarks *Bookmarks) tea.Cmd {
+	if bookmarks == nil || bookmarks.IsLoaded() {
+		return nil
+	}
+
+	return func() tea.Msg {
+		return bookmarksLoadedMsg{err: bookmarks.Load()}
+	}
+}
+
+// SetBookmarks sets the store bookmarks are saved to, which can be shared
+// with other models. Bookmarks are disabled when it is nil.
+func (m *Model) SetBookmarks(bookmarks *Bookmarks) tea.Cmd {
+	m.bookmarks = bookmarks
+
+	return m.routeCmd(loadBookmarksCmd(bookmarks))
+}
+
+// Bookmarks returns the store bookmarks are saved to.
+func (m Model) Bookmarks() *Bookmarks {
+	return m.bookmarks
+}
+
+// setBookmarkCmd saves the current directory under a mark.
+func (m *Model) setBookmarkCmd(mark rune) tea.Cmd {
+	if len(m.archives) > 0 || !filesystem.IsOS(m.fsys) {
+		m.setError(errors.New("bookmarks can only be set on the local disk"))
+
+		return nil
+	}
+
+	bookmarks := m.bookmarks
+	directory := m.currentDirectory
+
+	return func() tea.Msg {
+		return bookmarkSavedMsg{err: bookmarks.Set(mark, directory)}
+	}
+}
+
+// jumpToBookmark lists the directory saved under a mark.
+func (m *Model) jumpToBookmark(mark rune) tea.Cmd {
+	directory, ok := m.bookmarks.Get(mark)
+	if !ok {
+		m.setError(fmt.Errorf("no bookmark %q", mark))
+
+		return nil
+	}
+
+	return m.jumpToDirectory(directory)
+}
+
+// updateBookmarkInput handles the key press naming a new bookmark.
+func (m Model) updateBookmarkInput(msg tea.KeyMsg) (Model, tea.Cmd) {
+	m.stopInput()
+
+	if msg.Type == tea.KeyRunes && len(msg.Runes) == 1 && isBookmarkMark(msg.Runes[0]) {
+		return m, m.setBookmarkCmd(msg.Runes[0])
+	}
+
+	return m, nil
+}
@@ -1,22 +1,195 @@
 package filetree
 
 import (
-	"fmt"
+	"errors"
+	"io/fs"
 	"os"
 	"path/filepath"
+	"strings"
 
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/mistakenelf/teacup/filesystem"
+	"github.com/mistakenelf/teacup/icons"
 )
 
-type getDirectoryListingMsg []DirectoryItem
+type getDirectoryChildrenMsg struct {
+	parent string
+	files  []DirectoryItem
+}
+
 type errorMsg error
 
-// getDirectoryListingCmd updates the directory listing based on the name of the directory provided.
-func getDirectoryListingCmd(directoryName string, showHidden bool) tea.Cmd {
+// listingOptions controls which entries are included when listing a directory.
+type listingOptions struct {
+	showHidden     bool
+	showIgnored    bool
+	ignorePatterns []string
+	expanded       map[string]bool
+	withInfo       bool
+}
+
+// ignorer returns the ignorer used when listing a directory, or nil when ignored
+// entries are shown. Ignore files are read from the root of the git working tree
+// containing the directory, or from the root of the file system.
+func (o listingOptions) ignorer(fsys filesystem.FS, directoryName string) *filesystem.Ignorer {
+	if o.showIgnored {
+		return nil
+	}
+
+	root := filesystem.RootDirectory
+	if filesystem.IsOS(fsys) {
+		root = filepath.VolumeName(directoryName) + string(filepath.Separator)
+
+		if gitRoot, ok := filesystem.FindGitRoot(directoryName); ok {
+			root = gitRoot
+		}
+	}
+
+	return filesystem.NewIgnorer(fsys, root, o.ignorePatterns...)
+}
+
+// newDirectoryItem returns the item for a directory entry. Only the details known
+// from the entry are filled in, the rest are loaded with loadInfo when needed.
+func newDirectoryItem(directoryName string, entry fs.DirEntry, depth int) DirectoryItem {
+	extension := filepath.Ext(entry.Name())
+	indicator := icons.GetIndicator(entry.Type())
+	icon, iconColor := icons.GetIcon(strings.TrimSuffix(entry.Name(), extension), extension, indicator)
+
+	return DirectoryItem{
+		name:             entry.Name(),
+		path:             filepath.Join(directoryName, entry.Name()),
+		extension:        extension,
+		isDirectory:      entry.IsDir(),
+		currentDirectory: directoryName,
+		parent:           directoryName,
+		depth:            depth,
+		icon:             icon,
+		iconColor:        iconColor,
+		indicator:        indicator,
+		mode:             entry.Type(),
+		isSymlink:        entry.Type()&fs.ModeSymlink != 0,
+	}
+}
+
+// itemInfo holds the details of an item which require a call to stat. Symbolic
+// links also hold the details of their target, which is nil when the link is broken.
+type itemInfo struct {
+	info       fs.FileInfo
+	linkTarget string
+	targetInfo fs.FileInfo
+}
+
+// statItem reads the details of an item, leaving info empty when it can not be read.
+func statItem(fsys filesystem.FS, path string) itemInfo {
+	fileInfo, err := filesystem.Lstat(fsys, path)
+	if err != nil {
+		return itemInfo{}
+	}
+
+	info := itemInfo{info: fileInfo}
+	if fileInfo.Mode()&os.ModeSymlink != 0 {
+		info.linkTarget, _ = filesystem.ReadLink(fsys, path)
+		info.targetInfo, _ = fsys.Stat(path)
+	}
+
+	return info
+}
+
+// loadInfo fills in the details of an item.
+func (i *DirectoryItem) loadInfo(info itemInfo) {
+	i.loaded = true
+
+	if info.info == nil {
+		return
+	}
+
+	i.indicator = icons.GetIndicator(info.info.Mode())
+	i.icon, i.iconColor = icons.GetIcon(strings.TrimSuffix(i.name, i.extension), i.extension, i.indicator)
+	i.size = info.info.Size()
+	i.mode = info.info.Mode()
+	i.modTime = info.info.ModTime()
+	i.owner, i.group = filesystem.GetOwner(info.info)
+	i.isSymlink = info.info.Mode()&os.ModeSymlink != 0
+	i.linkTarget = info.linkTarget
+
+	if !i.isSymlink {
+		return
+	}
+
+	// Links are opened as whatever they point to, keeping the
+	// @ indicator while using the icon of the target.
+	i.isBrokenLink = info.targetInfo == nil
+	if !i.isBrokenLink {
+		i.isDirectory = info.targetInfo.IsDir()
+		i.icon, i.iconColor = icons.GetIcon(strings.TrimSuffix(i.name, i.extension), i.extension, icons.GetIndicator(info.targetInfo.Mode()))
+	}
+}
+
+// includeEntry reports if an entry should be listed.
+func includeEntry(directoryName string, entry fs.DirEntry, showHidden bool, ignorer *filesystem.Ignorer) bool {
+	if !showHidden && strings.HasPrefix(entry.Name(), ".") {
+		return false
+	}
+
+	return ignorer == nil || !ignorer.IsIgnored(filepath.Join(directoryName, entry.Name()), entry.IsDir())
+}
+
+// newDirectoryItems returns the items for the entries of a directory which should be listed.
+func newDirectoryItems(fsys filesystem.FS, directoryName string, entries []fs.DirEntry, depth int, options listingOptions, ignorer *filesystem.Ignorer) []DirectoryItem {
+	directoryItems := make([]DirectoryItem, 0, len(entries))
+
+	for _, entry := range entries {
+		if !includeEntry(directoryName, entry, options.showHidden, ignorer) {
+			continue
+		}
+
+		// Links are always read straight away as whether they point to a
+		// directory decides how they are sorted and opened.
+		item := newDirectoryItem(directoryName, entry, depth)
+		if options.withInfo || item.isSymlink {
+			item.loadInfo(statItem(fsys, item.path))
+		}
+
+		directoryItems = append(directoryItems, item)
+	}
+
+	return directoryItems
+}
+
+// readExpandedDirectoryItems reads a directory and recursively includes the children
+// of any directories which are expanded.
+func readExpandedDirectoryItems(fsys filesystem.FS, directoryName string, depth int, options listingOptions, ignorer *filesystem.Ignorer) ([]DirectoryItem, error) {
+	entries, err := fsys.ReadDir(directoryName)
+	if err != nil {
+		return nil, errors.Unwrap(err)
+	}
+
+	files := newDirectoryItems(fsys, directoryName, entries, depth, options, ignorer)
+	directoryItems := make([]DirectoryItem, 0, len(files))
+
+	for _, file := range files {
+		directoryItems = append(directoryItems, file)
+
+		if !file.isDirectory || !options.expanded[file.path] {
+			continue
+		}
+
+		children, err := readExpandedDirectoryItems(fsys, file.path, depth+1, options, ignorer)
+		if err != nil {
+			continue
+		}
+
+		directoryItems = append(directoryItems, children...)
+	}
+
+	return directoryItems, nil
+}
+
+// getDirectoryListingCmd starts listing a directory based on the name of the directory provided,
+// returning the first batch of items. Any expanded directories are listed along with their children.
+func getDirectoryListingCmd(fsys filesystem.FS, directoryName string, options listingOptions) tea.Cmd {
 	return func() tea.Msg {
 		var err error
-		var directoryItems []DirectoryItem
 
 		if directoryName == filesystem.HomeDirectory {
 			directoryName, err = filesystem.GetHomeDirectory()
@@ -25,7 +198,18 @@ func getDirectoryListingCmd(directoryName string, showHidden bool) tea.Cmd {
 			}
 		}
 
-		directoryInfo, err := os.Stat(directoryName)
+		// Only the local disk has a working directory, other
+		// backends resolve relative names against their root.
+		if filesystem.IsOS(fsys) {
+			directoryName, err = filepath.Abs(directoryName)
+			if err != nil {
+				return errorMsg(err)
+			}
+		} else {
+			directoryName = filepath.Join(filesystem.RootDirectory, directoryName)
+		}
+
+		directoryInfo, err := fsys.Stat(directoryName)
 		if err != nil {
 			return errorMsg(err)
 		}
@@ -34,42 +218,33 @@ func getDirectoryListingCmd(directoryName string, showHidden bool) tea.Cmd {
 			return nil
 		}
 
-		files, err := filesystem.GetDirectoryListing(directoryName, showHidden)
-		if err != nil {
-			return errorMsg(err)
-		}
+		return openDirectoryStream(fsys, directoryName, options)
+	}
+}
 
-		err = os.Chdir(directoryName)
+// followLinkCmd lists the directory a symbolic link resolves to.
+func followLinkCmd(fsys filesystem.FS, path string, options listingOptions) tea.Cmd {
+	return func() tea.Msg {
+		directoryName, err := filesystem.EvalSymlinks(fsys, path)
 		if err != nil {
 			return errorMsg(err)
 		}
 
-		workingDirectory, err := filesystem.GetWorkingDirectory()
+		return getDirectoryListingCmd(fsys, directoryName, options)()
+	}
+}
+
+// getDirectoryChildrenCmd lazily loads the children of an expanded directory in tree mode.
+func getDirectoryChildrenCmd(fsys filesystem.FS, item DirectoryItem, options listingOptions) tea.Cmd {
+	return func() tea.Msg {
+		directoryItems, err := readExpandedDirectoryItems(fsys, item.path, item.depth+1, options, options.ignorer(fsys, item.path))
 		if err != nil {
 			return errorMsg(err)
 		}
 
-		for _, file := range files {
-			fileInfo, err := file.Info()
-			if err != nil {
-				continue
-			}
-
-			status := fmt.Sprintf("%s %s %s",
-				fileInfo.ModTime().Format("2006-01-02 15:04:05"),
-				fileInfo.Mode().String(),
-				ConvertBytesToSizeString(fileInfo.Size()))
-
-			directoryItems = append(directoryItems, DirectoryItem{
-				name:             file.Name(),
-				details:          status,
-				path:             filepath.Join(workingDirectory, file.Name()),
-				extension:        filepath.Ext(fileInfo.Name()),
-				isDirectory:      fileInfo.IsDir(),
-				currentDirectory: workingDirectory,
-			})
+		return getDirectoryChildrenMsg{
+			parent: item.path,
+			files:  directoryItems,
 		}
-
-		return getDirectoryListingMsg(directoryItems)
 	}
 }
@@ -0,0 +1,148 @@
+package filetree
+
+import (
+	"strings"
+	"unicode"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/mistakenelf/teacup/filesystem"
+)
+
+// fuzzyMatch reports if all characters of the query appear in order within
+// the name, returning the positions of the matched runes.
+func fuzzyMatch(name, query string) ([]int, bool) {
+	queryRunes := []rune(strings.ToLower(query))
+	if len(queryRunes) == 0 {
+		return nil, true
+	}
+
+	matches := make([]int, 0, len(queryRunes))
+
+	for i, r := range []rune(name) {
+		if unicode.ToLower(r) == queryRunes[len(matches)] {
+			matches = append(matches, i)
+
+			if len(matches) == len(queryRunes) {
+				return matches, true
+			}
+		}
+	}
+
+	return nil, false
+}
+
+// matchesType reports if an item satisfies a listing type restriction.
+func matchesType(file DirectoryItem, listingType string) bool {
+	switch listingType {
+	case filesystem.DirectoriesListingType:
+		return file.isDirectory
+	case filesystem.FilesListingType:
+		return !file.isDirectory
+	}
+
+	return true
+}
+
+// filterFiles returns the items matching the query and listing type. The
+// parents of any matching items are kept so that the tree stays intact.
+func filterFiles(files []DirectoryItem, query, listingType string) []DirectoryItem {
+	if query == "" && listingType == "" {
+		return files
+	}
+
+	keep := make([]bool, len(files))
+	matches := make([][]int, len(files))
+	indexes := make(map[string]int, len(files))
+
+	for i, file := range files {
+		indexes[file.path] = i
+
+		if !matchesType(file, listingType) {
+			continue
+		}
+
+		positions, ok := fuzzyMatch(file.name, query)
+		if !ok {
+			continue
+		}
+
+		keep[i] = true
+		matches[i] = positions
+
+		for parent, ok := indexes[file.parent]; ok && !keep[parent]; parent, ok = indexes[files[parent].parent] {
+			keep[parent] = true
+		}
+	}
+
+	filtered := make([]DirectoryItem, 0, len(files))
+
+	for i, file := range files {
+		if keep[i] {
+			file.matches = matches[i]
+			filtered = append(filtered, file)
+		}
+	}
+
+	return layoutTree(filtered)
+}
+
+// applyFilter updates the visible items from the full listing, keeping
+// the cursor on the same item when it is still visible.
+func (m *Model) applyFilter() {
+	var selectedPath string
+	if len(m.files) > 0 {
+		selectedPath = m.files[m.cursor].path
+	}
+
+	m.files = filterFiles(m.allFiles, m.filter, m.filterType)
+	m.setCursor(0)
+
+	for i, file := range m.files {
+		if file.path == selectedPath {
+			m.setCursor(i)
+
+			break
+		}
+	}
+}
+
+// SetFilter sets the query used to fuzzy filter the current listing.
+func (m *Model) SetFilter(query string) {
+	m.filter = query
+	m.applyFilter()
+}
+
+// SetFilterType restricts the listing to only files or only directories using
+// filesystem.FilesListingType or filesystem.DirectoriesListingType, an empty
+// listing type shows everything.
+func (m *Model) SetFilterType(listingType string) {
+	m.filterType = listingType
+	m.applyFilter()
+}
+
+// startFiltering focuses the filter input.
+func (m *Model) startFiltering() tea.Cmd {
+	return m.startInput(filterInput, "/", m.filter)
+}
+
+// clearFilter removes the current filter and type restriction.
+func (m *Model) clearFilter() {
+	m.filter = ""
+	m.filterType = ""
+	m.stopInput()
+	m.applyFilter()
+}
+
+// filterStatus returns the filter shown below the listing once it is accepted.
+func (m Model) filterStatus() string {
+	if m.filter == "" && m.filterType == "" {
+		return ""
+	}
+
+	status := "/" + m.filter
+	if m.filterType != "" {
+		status += detailsStyle.Render(" [" + m.filterType + "]")
+	}
+
+	return status
+}
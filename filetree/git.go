@@ -0,0 +1,81 @@
+package filetree
+
+import (
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/mistakenelf/teacup/filesystem"
+)
+
+type gitStatusMsg struct {
+	directory string
+	statuses  *filesystem.GitStatuses
+}
+
+// gitStatusCmd reads the git status of a directory in the background.
+func gitStatusCmd(directory string, run filesystem.GitStatusRunner) tea.Cmd {
+	return func() tea.Msg {
+		// Decorations are best effort, a missing git binary
+		// should not be reported every time a directory is listed.
+		statuses, err := filesystem.GetGitStatus(directory, run)
+		if err != nil {
+			statuses = nil
+		}
+
+		return gitStatusMsg{
+			directory: directory,
+			statuses:  statuses,
+		}
+	}
+}
+
+// gitStatusCmd reads the git status of the current directory when enabled.
+func (m Model) gitStatusCmd() tea.Cmd {
+	if !m.showGitStatus || !filesystem.IsOS(m.fsys) || m.currentDirectory == "" {
+		return nil
+	}
+
+	return gitStatusCmd(m.currentDirectory, m.gitStatusRunner)
+}
+
+// SetShowGitStatus sets if items should be decorated with their git status.
+func (m *Model) SetShowGitStatus(showGitStatus bool) tea.Cmd {
+	m.showGitStatus = showGitStatus
+	m.gitStatuses = nil
+
+	return m.routeCmd(m.gitStatusCmd())
+}
+
+// SetGitStatusRunner sets the function used to run git status.
+func (m *Model) SetGitStatusRunner(run filesystem.GitStatusRunner) tea.Cmd {
+	m.gitStatusRunner = run
+	m.gitStatuses = nil
+
+	return m.routeCmd(m.gitStatusCmd())
+}
+
+// gitStatus returns the git status of an item.
+func (m Model) gitStatus(file DirectoryItem) filesystem.GitStatus {
+	if m.gitStatuses == nil {
+		return filesystem.GitUnmodified
+	}
+
+	return m.gitStatuses.Status(file.path)
+}
+
+// gitStatusMarker returns the marker and style shown for a git status.
+func gitStatusMarker(status filesystem.GitStatus) (string, lipgloss.Style) {
+	switch status {
+	case filesystem.GitIgnored:
+		return "!", gitIgnoredStyle
+	case filesystem.GitUntracked:
+		return "?", gitUntrackedStyle
+	case filesystem.GitStaged:
+		return "S", gitStagedStyle
+	case filesystem.GitModified:
+		return "M", gitModifiedStyle
+	case filesystem.GitConflicted:
+		return "U", gitConflictedStyle
+	}
+
+	return " ", lipgloss.NewStyle()
+}
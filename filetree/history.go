@@ -0,0 +1,167 @@
+package filetree
+
+import tea "github.com/charmbracelet/bubbletea"
+
+// defaultHistoryLimit is the number of directories kept in each direction
+// of the history and in the recent directories.
+const defaultHistoryLimit = 100
+
+// HistoryEntry is a directory which was visited along with the position
+// of the cursor and the visible window in its listing.
+type HistoryEntry struct {
+	Directory string
+	Cursor    int
+	Min       int
+	Max       int
+}
+
+// position returns a history entry for the current directory.
+func (m Model) position() HistoryEntry {
+	return HistoryEntry{
+		Directory: m.currentDirectory,
+		Cursor:    m.cursor,
+		Min:       m.min,
+		Max:       m.max,
+	}
+}
+
+// limitHistory drops the oldest entries beyond the history limit.
+func (m Model) limitHistory(entries []HistoryEntry) []HistoryEntry {
+	if len(entries) > m.historyLimit {
+		return append([]HistoryEntry(nil), entries[len(entries)-m.historyLimit:]...)
+	}
+
+	return entries
+}
+
+// pushHistory records the current directory before leaving it, forgetting
+// the directories which could be gone forward to.
+func (m *Model) pushHistory() {
+	if m.currentDirectory == "" || len(m.archives) > 0 {
+		return
+	}
+
+	m.backHistory = m.limitHistory(append(m.backHistory, m.position()))
+	m.forwardHistory = nil
+}
+
+// visitHistory lists a directory from the history, restoring the position
+// in its listing once it has been listed.
+func (m *Model) visitHistory(entry HistoryEntry) tea.Cmd {
+	m.closeArchives()
+	m.pendingHistory = &entry
+
+	return m.getDirectoryListingCmd(entry.Directory)
+}
+
+// restoreHistory moves the cursor and the visible window back to where
+// they were when a directory from the history was last visited.
+func (m *Model) restoreHistory(entry HistoryEntry) {
+	m.min = max(entry.Min, 0)
+	m.cursor = entry.Cursor
+	m.updateWindow()
+}
+
+// GoBack lists the previous directory in the history.
+func (m *Model) GoBack() tea.Cmd {
+	if len(m.backHistory) == 0 {
+		return nil
+	}
+
+	entry := m.backHistory[len(m.backHistory)-1]
+	m.backHistory = m.backHistory[:len(m.backHistory)-1]
+
+	if m.currentDirectory != "" && len(m.archives) == 0 {
+		m.forwardHistory = m.limitHistory(append(m.forwardHistory, m.position()))
+	}
+
+	return m.routeCmd(m.visitHistory(entry))
+}
+
+// GoForward lists the next directory in the history.
+func (m *Model) GoForward() tea.Cmd {
+	if len(m.forwardHistory) == 0 {
+		return nil
+	}
+
+	entry := m.forwardHistory[len(m.forwardHistory)-1]
+	m.forwardHistory = m.forwardHistory[:len(m.forwardHistory)-1]
+
+	if m.currentDirectory != "" && len(m.archives) == 0 {
+		m.backHistory = m.limitHistory(append(m.backHistory, m.position()))
+	}
+
+	return m.routeCmd(m.visitHistory(entry))
+}
+
+// CanGoBack reports if there is a previous directory in the history.
+func (m Model) CanGoBack() bool {
+	return len(m.backHistory) > 0
+}
+
+// CanGoForward reports if there is a next directory in the history.
+func (m Model) CanGoForward() bool {
+	return len(m.forwardHistory) > 0
+}
+
+// BackHistory returns the directories which can be gone back to, oldest first.
+func (m Model) BackHistory() []HistoryEntry {
+	return append([]HistoryEntry(nil), m.backHistory...)
+}
+
+// ForwardHistory returns the directories which can be gone forward to, the next one first.
+func (m Model) ForwardHistory() []HistoryEntry {
+	entries := make([]HistoryEntry, 0, len(m.forwardHistory))
+	for i := len(m.forwardHistory) - 1; i >= 0; i-- {
+		entries = append(entries, m.forwardHistory[i])
+	}
+
+	return entries
+}
+
+// SetHistoryLimit sets the number of directories kept in each direction
+// of the history and in the recent directories.
+func (m *Model) SetHistoryLimit(limit int) {
+	m.historyLimit = max(limit, 0)
+	m.backHistory = m.limitHistory(m.backHistory)
+	m.forwardHistory = m.limitHistory(m.forwardHistory)
+	m.recentDirectories = m.recentDirectories[:min(len(m.recentDirectories), m.historyLimit)]
+}
+
+// ClearHistory forgets every directory in the history and the recent directories.
+func (m *Model) ClearHistory() {
+	m.backHistory = nil
+	m.forwardHistory = nil
+	m.recentDirectories = nil
+}
+
+// addRecentDirectory moves a directory to the front of the recent directories.
+func (m *Model) addRecentDirectory(directory string) {
+	if len(m.archives) > 0 || m.historyLimit == 0 {
+		return
+	}
+
+	recent := make([]string, 0, len(m.recentDirectories)+1)
+	recent = append(recent, directory)
+
+	for _, recentDirectory := range m.recentDirectories {
+		if recentDirectory != directory && len(recent) < m.historyLimit {
+			recent = append(recent, recentDirectory)
+		}
+	}
+
+	m.recentDirectories = recent
+}
+
+// RecentDirectories returns the directories which were visited without
+// duplicates, the most recent first and not including the current directory.
+func (m Model) RecentDirectories() []string {
+	directories := make([]string, 0, len(m.recentDirectories))
+	for _, directory := range m.recentDirectories {
+		if directory != m.currentDirectory {
+			directories = append(directories, directory)
+		}
+	}
+
+	return directories
+}
@@ -6,5 +6,8 @@ import (
 )
 
 func (m Model) Init() tea.Cmd {
-	return getDirectoryListingCmd(filesystem.CurrentDirectory, true)
+	return m.routeCmd(tea.Batch(
+		m.getDirectoryListingCmd(filesystem.CurrentDirectory),
+		loadBookmarksCmd(m.bookmarks),
+	))
 }
@@ -0,0 +1,87 @@
+package filetree
+
+import (
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// startInput focuses the text input for the given mode.
+func (m *Model) startInput(mode inputMode, prompt, value string) tea.Cmd {
+	m.inputMode = mode
+	m.input.Prompt = prompt
+	m.input.SetValue(value)
+	m.input.CursorEnd()
+	m.input.Focus()
+	m.updateWindow()
+
+	return textinput.Blink
+}
+
+// startConfirmation asks for confirmation before deleting the target items.
+func (m *Model) startConfirmation() {
+	m.inputMode = confirmDeleteInput
+	m.updateWindow()
+}
+
+// isConfirming reports if the bubble is waiting for a permanent deletion to be confirmed.
+func (m Model) isConfirming() bool {
+	switch m.inputMode {
+	case confirmDeleteInput, confirmTrashDeleteInput, confirmEmptyTrashInput:
+		return true
+	}
+
+	return false
+}
+
+// stopInput blurs and resets the text input.
+func (m *Model) stopInput() {
+	m.inputMode = noInput
+	m.input.Blur()
+	m.input.Reset()
+	m.updateWindow()
+}
+
+// setError sets the error shown below the listing.
+func (m *Model) setError(err error) {
+	m.err = err
+	m.updateWindow()
+}
+
+// footer returns the line rendered below the listing, if any.
+func (m Model) footer() string {
+	switch m.inputMode {
+	case filterInput:
+		footer := m.input.View()
+		if m.filterType != "" {
+			footer += detailsStyle.Render(" [" + m.filterType + "]")
+		}
+
+		return footer
+	case selectGlobInput, createFileInput, createDirectoryInput, renameInput:
+		return m.input.View()
+	case confirmDeleteInput:
+		return confirmationStyle.Render(m.confirmationPrompt())
+	case setBookmarkInput:
+		return confirmationStyle.Render("Bookmark this directory as: (press a letter)")
+	case bookmarkPickerInput:
+		return detailsStyle.Render("press a letter or enter to jump, esc to cancel")
+	case recentPickerInput:
+		return detailsStyle.Render("press a number or enter to jump, esc to cancel")
+	case trashViewInput, confirmTrashDeleteInput, confirmEmptyTrashInput:
+		if m.inputMode == trashViewInput && m.err != nil {
+			break
+		}
+
+		return m.trashPrompt()
+	}
+
+	if m.err != nil {
+		return errorStyle.Render("Error: " + m.err.Error())
+	}
+
+	if m.stream != nil {
+		return m.loadingStatus()
+	}
+
+	return m.filterStatus()
+}
@@ -0,0 +1,71 @@
+package filetree
+
+import (
+	"io/fs"
+	"time"
+)
+
+// Name returns the name of the item.
+func (i DirectoryItem) Name() string {
+	return i.name
+}
+
+// Path returns the full path of the item.
+func (i DirectoryItem) Path() string {
+	return i.path
+}
+
+// Extension returns the extension of the item, including the dot.
+func (i DirectoryItem) Extension() string {
+	return i.extension
+}
+
+// IsDirectory reports if the item is a directory.
+func (i DirectoryItem) IsDirectory() bool {
+	return i.isDirectory
+}
+
+// Parent returns the directory containing the item.
+func (i DirectoryItem) Parent() string {
+	return i.parent
+}
+
+// Depth returns how deeply the item is nested below the current directory in tree mode.
+func (i DirectoryItem) Depth() int {
+	return i.depth
+}
+
+// Size returns the size of the item in bytes.
+func (i DirectoryItem) Size() int64 {
+	return i.size
+}
+
+// Mode returns the file mode of the item.
+func (i DirectoryItem) Mode() fs.FileMode {
+	return i.mode
+}
+
+// ModTime returns when the item was last modified.
+func (i DirectoryItem) ModTime() time.Time {
+	return i.modTime
+}
+
+// Owner returns the names of the user and group owning the item, when known.
+func (i DirectoryItem) Owner() (owner, group string) {
+	return i.owner, i.group
+}
+
+// LinkTarget returns the target of the item when it is a symbolic link.
+func (i DirectoryItem) LinkTarget() string {
+	return i.linkTarget
+}
+
+// IsSymlink reports if the item is a symbolic link.
+func (i DirectoryItem) IsSymlink() bool {
+	return i.isSymlink
+}
+
+// IsBrokenLink reports if the item is a symbolic link whose target does not exist.
+func (i DirectoryItem) IsBrokenLink() bool {
+	return i.isBrokenLink
+}
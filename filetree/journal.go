@@ -0,0 +1,38 @@
+package filetree
+
+import (
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/mistakenelf/teacup/filesystem"
+)
+
+// defaultUndoLimit is the number of batches of file operations which can be undone.
+const defaultUndoLimit = 100
+
+// SetJournal sets the journal file operations are recorded in, which can be
+// shared with other models. Operations can not be undone when it is nil.
+func (m *Model) SetJournal(journal *filesystem.Journal) {
+	m.journal = journal
+}
+
+// Journal returns the journal file operations are recorded in.
+func (m Model) Journal() *filesystem.Journal {
+	return m.journal
+}
+
+// undoCmd reverts the last batch of file operations. A batch which can not be
+// undone is discarded once its error is reported, so that it does not keep
+// the batches before it from being undone.
+func (m Model) undoCmd() tea.Cmd {
+	journal := m.journal
+
+	return fileOperationCmd(func() error {
+		batches := journal.Len()
+
+		err := journal.Undo()
+		if err != nil && journal.Len() == batches {
+			journal.Discard()
+		}
+
+		return err
+	}, "")
+}
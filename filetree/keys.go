@@ -1,15 +1,199 @@
 package filetree
 
-import "github.com/charmbracelet/bubbles/key"
+import (
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/mistakenelf/teacup/filesystem"
+)
 
+// KeyMap defines the key bindings of the bubble. It implements help.KeyMap,
+// use the methods of Model instead to only show the bindings which apply.
 type KeyMap struct {
-	Down key.Binding
-	Up   key.Binding
+	Down            key.Binding
+	Up              key.Binding
+	PageDown        key.Binding
+	PageUp          key.Binding
+	Top             key.Binding
+	Bottom          key.Binding
+	Open            key.Binding
+	Back            key.Binding
+	Home            key.Binding
+	Root            key.Binding
+	Tree            key.Binding
+	Details         key.Binding
+	ToggleHidden    key.Binding
+	ToggleIgnored   key.Binding
+	Sort            key.Binding
+	SortOrder       key.Binding
+	Filter          key.Binding
+	FilterType      key.Binding
+	Select          key.Binding
+	InvertSelection key.Binding
+	SelectAll       key.Binding
+	SelectGlob      key.Binding
+	ClearSelection  key.Binding
+	CreateFile      key.Binding
+	CreateDirectory key.Binding
+	Rename          key.Binding
+	Trash           key.Binding
+	Delete          key.Binding
+	Copy            key.Binding
+	Zip             key.Binding
+	Unzip           key.Binding
+	Extract         key.Binding
+	Undo            key.Binding
+	SetBookmark     key.Binding
+	JumpToBookmark  key.Binding
+	HistoryBack     key.Binding
+	HistoryForward  key.Binding
+	Recent          key.Binding
+	ShowTrash       key.Binding
+	Restore         key.Binding
+	EmptyTrash      key.Binding
+	Submit          key.Binding
+	Cancel          key.Binding
+	Confirm         key.Binding
 }
 
+// DefaultKeyMap returns the default key bindings.
 func DefaultKeyMap() KeyMap {
 	return KeyMap{
-		Down: key.NewBinding(key.WithKeys("j", "down", "ctrl+n"), key.WithHelp("j", "down")),
-		Up:   key.NewBinding(key.WithKeys("k", "up", "ctrl+p"), key.WithHelp("k", "up")),
+		Down:            key.NewBinding(key.WithKeys("j", "down", "ctrl+n"), key.WithHelp("j", "down")),
+		Up:              key.NewBinding(key.WithKeys("k", "up", "ctrl+p"), key.WithHelp("k", "up")),
+		PageDown:        key.NewBinding(key.WithKeys("pgdown", "ctrl+f"), key.WithHelp("pgdn", "page down")),
+		PageUp:          key.NewBinding(key.WithKeys("pgup", "ctrl+b"), key.WithHelp("pgup", "page up")),
+		Top:             key.NewBinding(key.WithKeys("g", "home"), key.WithHelp("g", "top")),
+		Bottom:          key.NewBinding(key.WithKeys("G", "end"), key.WithHelp("G", "bottom")),
+		Open:            key.NewBinding(key.WithKeys("enter", "l", "right"), key.WithHelp("l", "open")),
+		Back:            key.NewBinding(key.WithKeys("backspace", "h", "left"), key.WithHelp("h", "back")),
+		Home:            key.NewBinding(key.WithKeys("~"), key.WithHelp("~", "home")),
+		Root:            key.NewBinding(key.WithKeys("\\"), key.WithHelp("\\", "root")),
+		Tree:            key.NewBinding(key.WithKeys("t"), key.WithHelp("t", "tree mode")),
+		Details:         key.NewBinding(key.WithKeys("i"), key.WithHelp("i", "details")),
+		ToggleHidden:    key.NewBinding(key.WithKeys("."), key.WithHelp(".", "toggle hidden")),
+		ToggleIgnored:   key.NewBinding(key.WithKeys("I"), key.WithHelp("I", "toggle ignored")),
+		Sort:            key.NewBinding(key.WithKeys("s"), key.WithHelp("s", "sort by")),
+		SortOrder:       key.NewBinding(key.WithKeys("S"), key.WithHelp("S", "reverse sort")),
+		Filter:          key.NewBinding(key.WithKeys("/"), key.WithHelp("/", "filter")),
+		FilterType:      key.NewBinding(key.WithKeys("ctrl+t"), key.WithHelp("ctrl+t", "filter type")),
+		Select:          key.NewBinding(key.WithKeys(" "), key.WithHelp("space", "select")),
+		InvertSelection: key.NewBinding(key.WithKeys("v"), key.WithHelp("v", "invert selection")),
+		SelectAll:       key.NewBinding(key.WithKeys("a"), key.WithHelp("a", "select all")),
+		SelectGlob:      key.NewBinding(key.WithKeys("*"), key.WithHelp("*", "select by glob")),
+		ClearSelection:  key.NewBinding(key.WithKeys("esc"), key.WithHelp("esc", "clear selection")),
+		CreateFile:      key.NewBinding(key.WithKeys("n"), key.WithHelp("n", "new file")),
+		CreateDirectory: key.NewBinding(key.WithKeys("N"), key.WithHelp("N", "new directory")),
+		Rename:          key.NewBinding(key.WithKeys("r"), key.WithHelp("r", "rename")),
+		Trash:           key.NewBinding(key.WithKeys("d"), key.WithHelp("d", "trash")),
+		Delete:          key.NewBinding(key.WithKeys("D"), key.WithHelp("D", "delete")),
+		Copy:            key.NewBinding(key.WithKeys("c"), key.WithHelp("c", "copy")),
+		Zip:             key.NewBinding(key.WithKeys("z"), key.WithHelp("z", "zip")),
+		Unzip:           key.NewBinding(key.WithKeys("Z"), key.WithHelp("Z", "unzip")),
+		Extract:         key.NewBinding(key.WithKeys("x"), key.WithHelp("x", "extract")),
+		Undo:            key.NewBinding(key.WithKeys("u"), key.WithHelp("u", "undo")),
+		SetBookmark:     key.NewBinding(key.WithKeys("m"), key.WithHelp("m", "set bookmark")),
+		JumpToBookmark:  key.NewBinding(key.WithKeys("'"), key.WithHelp("'", "bookmarks")),
+		HistoryBack:     key.NewBinding(key.WithKeys("H"), key.WithHelp("H", "history back")),
+		HistoryForward:  key.NewBinding(key.WithKeys("L"), key.WithHelp("L", "history forward")),
+		Recent:          key.NewBinding(key.WithKeys("ctrl+r"), key.WithHelp("ctrl+r", "recent directories")),
+		ShowTrash:       key.NewBinding(key.WithKeys("T"), key.WithHelp("T", "show trash")),
+		Restore:         key.NewBinding(key.WithKeys("R"), key.WithHelp("R", "restore")),
+		EmptyTrash:      key.NewBinding(key.WithKeys("E"), key.WithHelp("E", "empty trash")),
+		Submit:          key.NewBinding(key.WithKeys("enter"), key.WithHelp("enter", "submit")),
+		Cancel:          key.NewBinding(key.WithKeys("esc"), key.WithHelp("esc", "cancel")),
+		Confirm:         key.NewBinding(key.WithKeys("y"), key.WithHelp("y", "confirm")),
 	}
 }
+
+// ShortHelp returns the most common bindings.
+func (k KeyMap) ShortHelp() []key.Binding {
+	return []key.Binding{k.Up, k.Down, k.Open, k.Back, k.Filter, k.Submit, k.Cancel, k.Confirm}
+}
+
+// FullHelp returns every binding, grouped into columns.
+func (k KeyMap) FullHelp() [][]key.Binding {
+	return [][]key.Binding{
+		{k.Up, k.Down, k.PageUp, k.PageDown, k.Top, k.Bottom, k.Open, k.Back, k.Home, k.Root},
+		{k.HistoryBack, k.HistoryForward, k.Recent, k.SetBookmark, k.JumpToBookmark},
+		{k.Tree, k.Details, k.ToggleHidden, k.ToggleIgnored, k.Sort, k.SortOrder, k.Filter, k.FilterType},
+		{k.Select, k.InvertSelection, k.SelectAll, k.SelectGlob, k.ClearSelection},
+		{k.CreateFile, k.CreateDirectory, k.Rename, k.Trash, k.Delete, k.Copy, k.Zip, k.Unzip, k.Extract, k.Undo},
+		{k.ShowTrash, k.Restore, k.EmptyTrash},
+		{k.Submit, k.Cancel, k.Confirm},
+	}
+}
+
+// activeKeyMap returns the key map with the bindings which do not apply
+// in the current state of the bubble disabled.
+func (m Model) activeKeyMap() KeyMap {
+	k := m.keyMap
+
+	// Bindings disabled in the key map stay disabled.
+	enable := func(enabled bool, bindings ...*key.Binding) {
+		for _, binding := range bindings {
+			binding.SetEnabled(binding.Enabled() && enabled)
+		}
+	}
+
+	item, hasItem := m.SelectedItem()
+	navigating := m.inputMode == noInput
+	writable := len(m.archives) == 0
+
+	// The trash has its own items, so only the bindings acting on them apply.
+	if m.isTrashView() {
+		_, hasItem = m.selectedTrashItem()
+	}
+
+	trashView := m.inputMode == trashViewInput
+
+	enable(navigating,
+		&k.Open, &k.Back, &k.Home, &k.Root, &k.Tree, &k.Details, &k.ToggleHidden, &k.ToggleIgnored,
+		&k.Sort, &k.SortOrder, &k.Filter, &k.Select, &k.InvertSelection, &k.SelectAll, &k.SelectGlob,
+		&k.ClearSelection, &k.CreateFile, &k.CreateDirectory, &k.Rename, &k.Trash, &k.Copy, &k.Zip,
+		&k.Unzip, &k.Extract, &k.SetBookmark, &k.JumpToBookmark, &k.HistoryBack, &k.HistoryForward,
+		&k.Recent, &k.PageUp, &k.PageDown)
+	enable(navigating || trashView, &k.Delete, &k.Top, &k.Bottom)
+	enable(hasItem, &k.Up, &k.Down, &k.PageUp, &k.PageDown, &k.Top, &k.Bottom, &k.Open, &k.Select,
+		&k.Rename, &k.Trash, &k.Delete, &k.Restore, &k.Copy, &k.Zip, &k.Unzip, &k.Extract)
+	enable(navigating || m.inputMode == filterInput || m.isPicking() || trashView, &k.Up, &k.Down)
+	enable(writable || trashView, &k.Delete)
+	enable(writable, &k.CreateFile, &k.CreateDirectory, &k.Rename, &k.Copy, &k.Zip, &k.Unzip)
+	enable(m.canTrash(), &k.Trash, &k.ShowTrash)
+	enable(navigating || trashView, &k.ShowTrash)
+	enable(trashView, &k.Restore)
+	enable(navigating && m.journal.Len() > 0, &k.Undo)
+	enable(trashView && len(m.trashItems) > 0, &k.EmptyTrash)
+	enable(hasItem && !item.isDirectory && filesystem.IsArchive(item.name), &k.Unzip)
+	enable(len(m.archives) > 0, &k.Extract)
+	enable(len(m.selection) > 0, &k.ClearSelection)
+	enable(m.bookmarks != nil, &k.SetBookmark, &k.JumpToBookmark)
+	enable(m.CanGoBack(), &k.HistoryBack)
+	enable(m.CanGoForward(), &k.HistoryForward)
+	enable(m.inputMode == filterInput, &k.FilterType)
+	enable(!navigating || m.filterStatus() != "" || m.IsLoading(), &k.Cancel)
+	enable(!navigating && !m.isConfirming() && m.inputMode != setBookmarkInput, &k.Submit)
+	enable(m.isConfirming(), &k.Confirm)
+
+	return k
+}
+
+// ShortHelp returns the most common bindings which apply in the current
+// state of the bubble, implementing help.KeyMap.
+func (m Model) ShortHelp() []key.Binding {
+	return m.activeKeyMap().ShortHelp()
+}
+
+// FullHelp returns every binding which applies in the current state
+// of the bubble, implementing help.KeyMap.
+func (m Model) FullHelp() [][]key.Binding {
+	return m.activeKeyMap().FullHelp()
+}
+
+// SetKeyMap sets the key bindings of the bubble.
+func (m *Model) SetKeyMap(keyMap KeyMap) {
+	m.keyMap = keyMap
+}
+
+// KeyMap returns the key bindings of the bubble.
+func (m Model) KeyMap() KeyMap {
+	return m.keyMap
+}
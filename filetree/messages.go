@@ -0,0 +1,68 @@
+package filetree
+
+import (
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/mistakenelf/teacup/filesystem"
+)
+
+// FileHighlightedMsg is sent when the cursor moves to another item, or
+// when a listing leaves no item highlighted, in which case Item is empty.
+type FileHighlightedMsg struct {
+	ID         int
+	Item       DirectoryItem
+	FileSystem filesystem.FS
+}
+
+// FileSelectedMsg is sent when a file, rather than a directory, is opened.
+type FileSelectedMsg struct {
+	ID         int
+	Item       DirectoryItem
+	FileSystem filesystem.FS
+}
+
+// DirectoryChangedMsg is sent when another directory is listed.
+type DirectoryChangedMsg struct {
+	ID         int
+	Directory  string
+	FileSystem filesystem.FS
+}
+
+// notifyCmd returns the messages for the parent app describing what
+// changed since the given directory and highlighted item. The highlighted
+// item is sent again once its details are loaded.
+func (m Model) notifyCmd(previousDirectory string, previousItem DirectoryItem) tea.Cmd {
+	var cmds []tea.Cmd
+
+	id, fsys := m.id, m.fsys
+
+	if m.currentDirectory != previousDirectory && m.currentDirectory != "" {
+		directory := m.currentDirectory
+		cmds = append(cmds, func() tea.Msg {
+			return DirectoryChangedMsg{ID: id, Directory: directory, FileSystem: fsys}
+		})
+	}
+
+	item, _ := m.SelectedItem()
+	if item.path != previousItem.path || m.currentDirectory != previousDirectory ||
+		(item.loaded && !previousItem.loaded) {
+		cmds = append(cmds, func() tea.Msg {
+			return FileHighlightedMsg{ID: id, Item: item, FileSystem: fsys}
+		})
+	}
+
+	return tea.Batch(cmds...)
+}
+
+// fileSelectedCmd tells the parent app a file was opened, loading
+// its details first if they were not loaded yet.
+func (m Model) fileSelectedCmd(item DirectoryItem) tea.Cmd {
+	id, fsys := m.id, m.fsys
+
+	return func() tea.Msg {
+		if !item.loaded {
+			item.loadInfo(statItem(fsys, item.path))
+		}
+
+		return FileSelectedMsg{ID: id, Item: item, FileSystem: fsys}
+	}
+}
@@ -1,20 +1,24 @@
 package filetree
 
-import "fmt"
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/mistakenelf/teacup/filesystem"
+)
 
 const (
 	thousand    = 1000
+	kibi        = 1024
 	ten         = 10
 	fivePercent = 0.0499
 )
 
 // ConvertBytesToSizeString converts a byte count to a human readable string.
 func ConvertBytesToSizeString(size int64) string {
-	if size < thousand {
-		return fmt.Sprintf("%dB", size)
-	}
-
-	suffix := []string{
+	return convertBytesToSizeString(size, thousand, []string{
 		"K", // kilo
 		"M", // mega
 		"G", // giga
@@ -23,16 +27,48 @@ func ConvertBytesToSizeString(size int64) string {
 		"E", // exa
 		"Z", // zeta
 		"Y", // yotta
+	})
+}
+
+// ConvertBytesToSizeStringIEC converts a byte count to a human readable string
+// using binary (powers of 1024) units.
+func ConvertBytesToSizeStringIEC(size int64) string {
+	return convertBytesToSizeString(size, kibi, []string{
+		"Ki", // kibi
+		"Mi", // mebi
+		"Gi", // gibi
+		"Ti", // tebi
+		"Pi", // pebi
+		"Ei", // exbi
+		"Zi", // zebi
+		"Yi", // yobi
+	})
+}
+
+// FormatSize converts a byte count to a human readable string in the given format.
+func FormatSize(size int64, format SizeFormat) string {
+	if format == IECSizeFormat {
+		return ConvertBytesToSizeStringIEC(size)
+	}
+
+	return ConvertBytesToSizeString(size)
+}
+
+// convertBytesToSizeString converts a byte count to a human readable string
+// given the base of each unit and its suffixes.
+func convertBytesToSizeString(size int64, base float64, suffix []string) string {
+	if float64(size) < base {
+		return fmt.Sprintf("%dB", size)
 	}
 
-	curr := float64(size) / thousand
+	curr := float64(size) / base
 	for _, s := range suffix {
 		if curr < ten {
 			return fmt.Sprintf("%.1f%s", curr-fivePercent, s)
-		} else if curr < thousand {
+		} else if curr < base {
 			return fmt.Sprintf("%d%s", int(curr), s)
 		}
-		curr /= thousand
+		curr /= base
 	}
 
 	return ""
@@ -42,3 +78,395 @@ func ConvertBytesToSizeString(size int64) string {
 func (m *Model) SetIsActive(active bool) {
 	m.active = active
 }
+
+// SetSize sets the size of the bubble.
+func (m *Model) SetSize(width, height int) {
+	m.width = width
+	m.height = height
+	m.updateWindow()
+}
+
+// SetDirectory lists a directory, highlighting the item at selectPath
+// once it is listed unless it is empty.
+func (m *Model) SetDirectory(directory, selectPath string) tea.Cmd {
+	m.returnPath = selectPath
+
+	return m.routeCmd(m.getDirectoryListingCmd(directory))
+}
+
+// setCursor moves the cursor to the given index, scrolling the
+// visible window so the cursor stays on screen.
+func (m *Model) setCursor(index int) {
+	if index >= len(m.files) {
+		index = len(m.files) - 1
+	}
+
+	if index < 0 {
+		index = 0
+	}
+
+	m.cursor = index
+
+	if m.cursor < m.min {
+		m.max -= m.min - m.cursor
+		m.min = m.cursor
+	}
+
+	if m.cursor > m.max {
+		m.min += m.cursor - m.max
+		m.max = m.cursor
+	}
+}
+
+// resetCursor moves the cursor and the visible window back to the top.
+func (m *Model) resetCursor() {
+	m.cursor = 0
+	m.min = 0
+	m.max = max(m.listHeight()-1, 0)
+}
+
+// listHeight returns the number of rows available for the listing.
+func (m Model) listHeight() int {
+	if m.footer() != "" {
+		return m.height - 1
+	}
+
+	return m.height
+}
+
+// updateWindow resizes the visible window to fit the available rows.
+func (m *Model) updateWindow() {
+	m.max = m.min + m.listHeight() - 1
+	m.setCursor(m.cursor)
+}
+
+// openSelectedItem opens the highlighted item. Directories are listed, or expanded
+// in tree mode, archives are browsed and the parent app is told about other files.
+func (m *Model) openSelectedItem() tea.Cmd {
+	item, ok := m.SelectedItem()
+	if !ok {
+		return nil
+	}
+
+	switch {
+	case item.isBrokenLink:
+		m.setError(fmt.Errorf("%s is a broken link to %s", item.name, item.linkTarget))
+
+		return nil
+	case !item.isDirectory && filesystem.IsArchive(item.name):
+		return openArchiveCmd(m.fsys, item.path)
+	case !item.isDirectory:
+		return m.fileSelectedCmd(item)
+	case m.treeMode:
+		return m.toggleDirectory(item)
+	}
+
+	if item.isSymlink && m.followLinks {
+		return followLinkCmd(m.fsys, item.path, m.listingOptions())
+	}
+
+	return m.getDirectoryListingCmd(item.path)
+}
+
+// SelectedItem returns the highlighted item, reporting false when the listing is empty.
+func (m Model) SelectedItem() (DirectoryItem, bool) {
+	if len(m.files) == 0 {
+		return DirectoryItem{}, false
+	}
+
+	return m.files[m.cursor], true
+}
+
+// CurrentDirectory returns the directory being listed.
+func (m Model) CurrentDirectory() string {
+	return m.currentDirectory
+}
+
+// Items returns the items which are listed, after filtering.
+func (m Model) Items() []DirectoryItem {
+	return append([]DirectoryItem(nil), m.files...)
+}
+
+// SetShowIcons sets if icons and indicators should be rendered next to each item,
+// this can be turned off for terminals without a Nerd Font.
+func (m *Model) SetShowIcons(showIcons bool) {
+	m.showIcons = showIcons
+}
+
+// SetShowDetails sets if the detail columns should be shown next to each item.
+func (m *Model) SetShowDetails(showDetails bool) {
+	m.showDetails = showDetails
+}
+
+// SetColumns sets which detail columns are shown and in which order.
+func (m *Model) SetColumns(columns ...Column) {
+	m.columns = columns
+}
+
+// ToggleColumn shows a detail column if it is hidden, or hides it if it is shown.
+func (m *Model) ToggleColumn(column Column) {
+	for i, c := range m.columns {
+		if c == column {
+			m.columns = append(m.columns[:i:i], m.columns[i+1:]...)
+
+			return
+		}
+	}
+
+	m.columns = append(m.columns, column)
+}
+
+// SetTimeFormat sets the layout used to render modification times.
+func (m *Model) SetTimeFormat(timeFormat string) {
+	m.timeFormat = timeFormat
+}
+
+// SetSizeFormat sets the units used to render file sizes.
+func (m *Model) SetSizeFormat(sizeFormat SizeFormat) {
+	m.sizeFormat = sizeFormat
+}
+
+// SetSortBy sets the field items are sorted by.
+func (m *Model) SetSortBy(sortBy SortBy) {
+	m.sortBy = sortBy
+	m.resort()
+}
+
+// SetSortOrder sets the direction items are sorted in.
+func (m *Model) SetSortOrder(sortOrder SortOrder) {
+	m.sortOrder = sortOrder
+	m.resort()
+}
+
+// SetDirectoriesFirst sets if directories are listed before files.
+func (m *Model) SetDirectoriesFirst(directoriesFirst bool) {
+	m.directoriesFirst = directoriesFirst
+	m.resort()
+}
+
+// SetNaturalSort sets if numbers within names are compared by value,
+// so that file2 is ordered before file10.
+func (m *Model) SetNaturalSort(naturalSort bool) {
+	m.naturalSort = naturalSort
+	m.resort()
+}
+
+// SetWatch sets if the current directory is watched so that the listing
+// refreshes automatically when it changes on disk.
+func (m *Model) SetWatch(watch bool) tea.Cmd {
+	m.watch = watch
+
+	if !watch {
+		m.stopWatching()
+
+		return nil
+	}
+
+	if m.watcher == nil && m.currentDirectory != "" && filesystem.IsOS(m.fsys) {
+		return m.routeCmd(watchDirectoryCmd(m.currentDirectory))
+	}
+
+	return nil
+}
+
+// SetFollowLinks sets if opening a symbolic link to a directory lists the
+// directory it resolves to, instead of listing it through the link.
+func (m *Model) SetFollowLinks(followLinks bool) {
+	m.followLinks = followLinks
+}
+
+// Close stops listing and watching the current directory, it should be called
+// once the bubble is no longer in use.
+func (m *Model) Close() {
+	m.CancelLoading()
+	m.stopWatching()
+	m.closeArchives()
+}
+
+// stopWatching stops the watcher of the current directory, if any.
+func (m *Model) stopWatching() {
+	if m.watcher != nil {
+		m.watcher.Close()
+		m.watcher = nil
+	}
+}
+
+// SetFileSystem sets the backend which is browsed, listing its root
+// or the working directory for the local disk.
+func (m *Model) SetFileSystem(fsys filesystem.FS) tea.Cmd {
+	m.closeArchives()
+	m.switchFileSystem(fsys)
+	m.ClearHistory()
+	m.allFiles = nil
+	m.files = nil
+	m.setError(nil)
+
+	return m.routeCmd(m.getDirectoryListingCmd(filesystem.CurrentDirectory))
+}
+
+// FileSystem returns the backend which is browsed, so that viewers
+// can read the files it contains.
+func (m Model) FileSystem() filesystem.FS {
+	return m.fsys
+}
+
+// SetShowHidden sets if files and directories starting with a dot are listed.
+func (m *Model) SetShowHidden(showHidden bool) tea.Cmd {
+	m.showHidden = showHidden
+
+	return m.routeCmd(m.relist())
+}
+
+// SetShowIgnored sets if entries matched by ignore files or
+// the extra ignore patterns are listed.
+func (m *Model) SetShowIgnored(showIgnored bool) tea.Cmd {
+	m.showIgnored = showIgnored
+
+	return m.routeCmd(m.relist())
+}
+
+// SetIgnorePatterns sets extra gitignore style patterns which are
+// hidden along with ignored entries.
+func (m *Model) SetIgnorePatterns(patterns ...string) tea.Cmd {
+	m.ignorePatterns = patterns
+
+	return m.routeCmd(m.relist())
+}
+
+// relist lists the current directory again, keeping the highlighted item
+// selected if it is still listed.
+func (m *Model) relist() tea.Cmd {
+	if m.currentDirectory == "" {
+		return nil
+	}
+
+	if len(m.files) > 0 {
+		m.returnPath = m.files[m.cursor].path
+	}
+
+	return m.getDirectoryListingCmd(m.currentDirectory)
+}
+
+// SetTreeMode sets if directories should expand and collapse in place
+// instead of being navigated into.
+func (m *Model) SetTreeMode(treeMode bool) tea.Cmd {
+	m.treeMode = treeMode
+
+	return m.routeCmd(m.relist())
+}
+
+// getDirectoryListingCmd lists a directory using the current settings of the bubble.
+// Relative names are resolved against the current directory of the bubble.
+func (m Model) getDirectoryListingCmd(directoryName string) tea.Cmd {
+	if directoryName != filesystem.HomeDirectory && !filepath.IsAbs(directoryName) && m.currentDirectory != "" {
+		directoryName = filepath.Join(m.currentDirectory, directoryName)
+	}
+
+	return getDirectoryListingCmd(m.fsys, directoryName, m.listingOptions())
+}
+
+// listingOptions returns the options used to list directories. The expanded
+// directories are copied so that commands do not share the map with the model.
+func (m Model) listingOptions() listingOptions {
+	options := listingOptions{
+		showHidden:     m.showHidden,
+		showIgnored:    m.showIgnored,
+		ignorePatterns: m.ignorePatterns,
+		withInfo:       m.sortNeedsInfo(),
+	}
+
+	if m.treeMode {
+		options.expanded = make(map[string]bool, len(m.expanded))
+		for path := range m.expanded {
+			options.expanded[path] = true
+		}
+	}
+
+	return options
+}
+
+// toggleDirectory expands or collapses a directory in tree mode.
+func (m *Model) toggleDirectory(item DirectoryItem) tea.Cmd {
+	if m.expanded[item.path] {
+		delete(m.expanded, item.path)
+
+		for i, file := range m.allFiles {
+			if file.path == item.path {
+				m.allFiles = layoutTree(append(m.allFiles[:i+1], m.allFiles[descendantsEnd(m.allFiles, i):]...))
+
+				break
+			}
+		}
+
+		m.applyFilter()
+
+		return nil
+	}
+
+	m.expanded[item.path] = true
+
+	return getDirectoryChildrenCmd(m.fsys, item, m.listingOptions())
+}
+
+// descendantsEnd returns the index just past the last descendant
+// of the item at the given index.
+func descendantsEnd(files []DirectoryItem, index int) int {
+	end := index + 1
+	for end < len(files) && files[end].depth > files[index].depth {
+		end++
+	}
+
+	return end
+}
+
+// layoutTree calculates the guide lines drawn in front of each item
+// based on where it sits in the hierarchy.
+func layoutTree(files []DirectoryItem) []DirectoryItem {
+	isLast := make([]bool, len(files))
+	hasNext := []bool{}
+
+	// Walk backwards so that we know if an item has any
+	// siblings below it.
+	for i := len(files) - 1; i >= 0; i-- {
+		depth := files[i].depth
+		for len(hasNext) <= depth {
+			hasNext = append(hasNext, false)
+		}
+
+		isLast[i] = !hasNext[depth]
+		hasNext[depth] = true
+		hasNext = hasNext[:depth+1]
+	}
+
+	continues := []bool{}
+
+	for i := range files {
+		var guide strings.Builder
+
+		depth := files[i].depth
+		for len(continues) <= depth {
+			continues = append(continues, false)
+		}
+
+		for level := 1; level < depth; level++ {
+			if continues[level] {
+				guide.WriteString("│   ")
+			} else {
+				guide.WriteString("    ")
+			}
+		}
+
+		if depth > 0 {
+			if isLast[i] {
+				guide.WriteString("└── ")
+			} else {
+				guide.WriteString("├── ")
+			}
+		}
+
+		continues[depth] = !isLast[i]
+		files[i].guide = guide.String()
+	}
+
+	return files
+}
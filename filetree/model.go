@@ -1,31 +1,221 @@
 package filetree
 
+import (
+	"io/fs"
+	"time"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	"github.com/mistakenelf/teacup/filesystem"
+)
+
+// Column represents a detail column which can be shown next to each item.
+type Column int
+
+// Available detail columns.
+const (
+	SizeColumn Column = iota
+	PermissionsColumn
+	OwnerColumn
+	ModifiedColumn
+	LinkTargetColumn
+)
+
+// SizeFormat represents the units used to render file sizes.
+type SizeFormat int
+
+// Available size formats.
+const (
+	// SISizeFormat renders sizes in powers of 1000 (K, M, G).
+	SISizeFormat SizeFormat = iota
+	// IECSizeFormat renders sizes in powers of 1024 (Ki, Mi, Gi).
+	IECSizeFormat
+)
+
+// SortBy represents the field items are sorted by.
+type SortBy int
+
+// Available sort fields.
+const (
+	SortByName SortBy = iota
+	SortBySize
+	SortByModTime
+	SortByExtension
+	SortByType
+)
+
+// SortOrder represents the direction items are sorted in.
+type SortOrder int
+
+// Available sort orders.
+const (
+	Ascending SortOrder = iota
+	Descending
+)
+
+// inputMode represents what the text input is currently being used for.
+type inputMode int
+
+const (
+	noInput inputMode = iota
+	filterInput
+	selectGlobInput
+	createFileInput
+	createDirectoryInput
+	renameInput
+	confirmDeleteInput
+	setBookmarkInput
+	bookmarkPickerInput
+	recentPickerInput
+	trashViewInput
+	confirmTrashDeleteInput
+	confirmEmptyTrashInput
+)
+
 type DirectoryItem struct {
 	name             string
-	details          string
 	path             string
 	extension        string
 	isDirectory      bool
 	currentDirectory string
+	parent           string
+	depth            int
+	guide            string
+	icon             string
+	iconColor        string
+	indicator        string
+	size             int64
+	mode             fs.FileMode
+	modTime          time.Time
+	owner            string
+	group            string
+	linkTarget       string
+	isSymlink        bool
+	isBrokenLink     bool
+	matches          []int
+	loaded           bool
 }
 
 type Model struct {
-	cursor int
-	files  []DirectoryItem
-	active bool
-	keyMap KeyMap
-	min    int
-	max    int
-	height int
-	width  int
+	cursor            int
+	files             []DirectoryItem
+	allFiles          []DirectoryItem
+	active            bool
+	keyMap            KeyMap
+	min               int
+	max               int
+	height            int
+	width             int
+	showHidden        bool
+	showIgnored       bool
+	ignorePatterns    []string
+	currentDirectory  string
+	returnPath        string
+	treeMode          bool
+	expanded          map[string]bool
+	showIcons         bool
+	showDetails       bool
+	columns           []Column
+	timeFormat        string
+	sizeFormat        SizeFormat
+	sortBy            SortBy
+	sortOrder         SortOrder
+	directoriesFirst  bool
+	naturalSort       bool
+	input             textinput.Model
+	inputMode         inputMode
+	filter            string
+	filterType        string
+	selection         map[string]DirectoryItem
+	err               error
+	watch             bool
+	watcher           *watcher
+	fsys              filesystem.FS
+	archives          []archive
+	showGitStatus     bool
+	gitStatusRunner   filesystem.GitStatusRunner
+	gitStatuses       *filesystem.GitStatuses
+	bookmarks         *Bookmarks
+	pickerCursor      int
+	backHistory       []HistoryEntry
+	forwardHistory    []HistoryEntry
+	pendingHistory    *HistoryEntry
+	recentDirectories []string
+	historyLimit      int
+	originX           int
+	originY           int
+	lastClick         time.Time
+	lastClickIndex    int
+	stream            *directoryStream
+	streamFiles       []DirectoryItem
+	streamCount       int
+	streamReplace     bool
+	loadingItems      map[string]bool
+	followLinks       bool
+	trashItems        []filesystem.TrashItem
+	trashCursor       int
+	journal           *filesystem.Journal
+	id                int
 }
 
-func New() Model {
-	return Model{
-		cursor: 0,
-		active: true,
-		keyMap: DefaultKeyMap(),
-		min:    0,
-		max:    0,
+// Option configures a Model created by New.
+type Option func(*Model)
+
+// WithKeyMap replaces the default key bindings.
+func WithKeyMap(keyMap KeyMap) Option {
+	return func(m *Model) {
+		m.keyMap = keyMap
+	}
+}
+
+// WithBookmarks sets the store bookmarks are saved to. Bookmarks are only kept
+// in memory by default, to keep them between runs save them to the default file:
+//
+//	path, err := filetree.DefaultBookmarksPath()
+//	if err != nil {
+//		return err
+//	}
+//
+//	m := filetree.New(filetree.WithBookmarks(filetree.NewBookmarks(path)))
+func WithBookmarks(bookmarks *Bookmarks) Option {
+	return func(m *Model) {
+		m.bookmarks = bookmarks
 	}
 }
+
+func New(opts ...Option) Model {
+	input := textinput.New()
+
+	m := Model{
+		cursor:          0,
+		active:          true,
+		keyMap:          DefaultKeyMap(),
+		min:             0,
+		max:             0,
+		showHidden:      true,
+		showIgnored:     true,
+		expanded:        make(map[string]bool),
+		showIcons:       true,
+		columns:         []Column{SizeColumn, PermissionsColumn, ModifiedColumn},
+		timeFormat:      "2006-01-02 15:04",
+		sizeFormat:      SISizeFormat,
+		sortBy:          SortByName,
+		sortOrder:       Ascending,
+		input:           input,
+		selection:       make(map[string]DirectoryItem),
+		watch:           true,
+		fsys:            filesystem.OSFS{},
+		showGitStatus:   true,
+		gitStatusRunner: filesystem.RunGitStatus,
+		bookmarks:       NewBookmarks(""),
+		historyLimit:    defaultHistoryLimit,
+		loadingItems:    make(map[string]bool),
+		journal:         filesystem.NewJournal(defaultUndoLimit),
+		id:              nextID(),
+	}
+
+	for _, opt := range opts {
+		opt(&m)
+	}
+
+	return m
+}
@@ -0,0 +1,89 @@
+package filetree
+
+import (
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+const (
+	// doubleClickInterval is the longest time between two clicks on the same row
+	// for them to count as a double click.
+	doubleClickInterval = 400 * time.Millisecond
+
+	// wheelRows is the number of rows scrolled by each turn of the mouse wheel.
+	wheelRows = 3
+)
+
+// SetOrigin sets the position of the top left corner of the bubble on the screen,
+// so that mouse events can be mapped to rows when it is not drawn at the top left.
+func (m *Model) SetOrigin(x, y int) {
+	m.originX = x
+	m.originY = y
+}
+
+// rowAt returns the index of the item drawn at a position on the screen.
+func (m Model) rowAt(x, y int) (int, bool) {
+	x -= m.originX
+	y -= m.originY
+
+	if x < 0 || y < 0 || (m.width > 0 && x >= m.width) || y >= m.listHeight() {
+		return 0, false
+	}
+
+	index := m.min + y
+	if index >= len(m.files) {
+		return 0, false
+	}
+
+	return index, true
+}
+
+// scroll moves the visible window by a number of rows, keeping the cursor on screen.
+func (m *Model) scroll(rows int) {
+	height := max(m.listHeight(), 1)
+
+	m.min = min(max(m.min+rows, 0), max(len(m.files)-height, 0))
+	m.max = m.min + height - 1
+	m.cursor = min(max(m.cursor, m.min), min(m.max, max(len(m.files)-1, 0)))
+}
+
+// handleMouse selects the clicked row, opens it on a double click
+// and scrolls with the wheel.
+func (m *Model) handleMouse(msg tea.MouseMsg) tea.Cmd {
+	// The listing is replaced or waiting on the footer while input is taken.
+	if m.inputMode != noInput && m.inputMode != filterInput {
+		return nil
+	}
+
+	x, y := msg.X-m.originX, msg.Y-m.originY
+	inside := x >= 0 && y >= 0 && (m.width == 0 || x < m.width) && (m.height == 0 || y < m.height)
+
+	switch {
+	case !inside:
+		return nil
+	case msg.Button == tea.MouseButtonWheelUp:
+		m.scroll(-wheelRows)
+	case msg.Button == tea.MouseButtonWheelDown:
+		m.scroll(wheelRows)
+	case msg.Button == tea.MouseButtonLeft && msg.Action == tea.MouseActionPress:
+		index, ok := m.rowAt(msg.X, msg.Y)
+		if !ok {
+			return nil
+		}
+
+		doubleClick := index == m.lastClickIndex && time.Since(m.lastClick) < doubleClickInterval
+		m.setCursor(index)
+
+		if doubleClick {
+			m.lastClick = time.Time{}
+
+			return m.openSelectedItem()
+		}
+
+		m.lastClick = time.Now()
+		m.lastClickIndex = index
+	}
+
+	return nil
+}
@@ -0,0 +1,175 @@
+package filetree
+
+import (
+	"fmt"
+	"path/filepath"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/mistakenelf/teacup/filesystem"
+)
+
+type fileOperationMsg struct {
+	selectPath string
+	err        error
+}
+
+// fileOperationCmd runs a file operation, selecting the given path once the
+// listing has been refreshed.
+func fileOperationCmd(operation func() error, selectPath string) tea.Cmd {
+	return func() tea.Msg {
+		return fileOperationMsg{
+			selectPath: selectPath,
+			err:        operation(),
+		}
+	}
+}
+
+// journaledCmd runs file operations as a single batch, recording it in the
+// journal so that it can be undone, then selects the given path.
+func (m Model) journaledCmd(operation func(batch *filesystem.Batch) error, selectPath string) tea.Cmd {
+	return m.journaledCmdFS(m.fsys, operation, selectPath)
+}
+
+// journaledCmdFS runs file operations on a backend other than the one being
+// browsed as a single batch, in the same way as journaledCmd.
+func (m Model) journaledCmdFS(fsys filesystem.FS, operation func(batch *filesystem.Batch) error, selectPath string) tea.Cmd {
+	journal := m.journal
+
+	return fileOperationCmd(func() error {
+		batch := filesystem.NewBatch(fsys)
+		err := operation(batch)
+		journal.Record(batch)
+
+		return err
+	}, selectPath)
+}
+
+// targetItems returns the marked items, or the highlighted item when nothing is marked.
+func (m Model) targetItems() []DirectoryItem {
+	if len(m.selection) > 0 {
+		return m.SelectedItems()
+	}
+
+	if len(m.files) == 0 {
+		return nil
+	}
+
+	return []DirectoryItem{m.files[m.cursor]}
+}
+
+// targetDirectory returns the directory new items are created in, which is
+// the directory containing the highlighted item.
+func (m Model) targetDirectory() string {
+	if len(m.files) == 0 {
+		return m.currentDirectory
+	}
+
+	return m.files[m.cursor].parent
+}
+
+// refresh lists the current directory again, selecting the given path.
+func (m *Model) refresh(selectPath string) tea.Cmd {
+	m.returnPath = selectPath
+
+	return m.getDirectoryListingCmd(m.currentDirectory)
+}
+
+// createFileCmd creates a new file in the target directory.
+func (m Model) createFileCmd(name string) tea.Cmd {
+	path := filepath.Join(m.targetDirectory(), name)
+
+	return m.journaledCmd(func(batch *filesystem.Batch) error {
+		return batch.CreateFile(path)
+	}, path)
+}
+
+// createDirectoryCmd creates a new directory in the target directory.
+func (m Model) createDirectoryCmd(name string) tea.Cmd {
+	path := filepath.Join(m.targetDirectory(), name)
+
+	return m.journaledCmd(func(batch *filesystem.Batch) error {
+		return batch.CreateDirectory(path)
+	}, path)
+}
+
+// renameCmd renames the highlighted item.
+func (m Model) renameCmd(name string) tea.Cmd {
+	item := m.files[m.cursor]
+	path := filepath.Join(item.parent, name)
+
+	return m.journaledCmd(func(batch *filesystem.Batch) error {
+		return batch.Rename(item.path, path)
+	}, path)
+}
+
+// deleteCmd permanently deletes the target items.
+func (m Model) deleteCmd() tea.Cmd {
+	fsys := m.fsys
+	items := m.targetItems()
+
+	return fileOperationCmd(func() error {
+		for _, item := range items {
+			var err error
+			if item.isDirectory {
+				err = filesystem.DeleteDirectoryFS(fsys, item.path)
+			} else {
+				err = filesystem.DeleteFileFS(fsys, item.path)
+			}
+
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}, "")
+}
+
+// copyCmd copies the target items.
+func (m Model) copyCmd() tea.Cmd {
+	items := m.targetItems()
+
+	return m.journaledCmd(func(batch *filesystem.Batch) error {
+		for _, item := range items {
+			if err := batch.Copy(item.path, item.parent); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}, m.files[m.cursor].path)
+}
+
+// zipCmd zips the target items.
+func (m Model) zipCmd() tea.Cmd {
+	items := m.targetItems()
+
+	return m.journaledCmd(func(batch *filesystem.Batch) error {
+		for _, item := range items {
+			if err := batch.Zip(item.path, item.parent); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}, m.files[m.cursor].path)
+}
+
+// unzipCmd unzips the highlighted item.
+func (m Model) unzipCmd() tea.Cmd {
+	item := m.files[m.cursor]
+
+	return m.journaledCmd(func(batch *filesystem.Batch) error {
+		return batch.Unzip(item.path)
+	}, item.path)
+}
+
+// confirmationPrompt returns the question asked before permanently deleting the target items.
+func (m Model) confirmationPrompt() string {
+	items := m.targetItems()
+	if len(items) == 1 {
+		return fmt.Sprintf("Permanently delete %s? (y/n)", items[0].name)
+	}
+
+	return fmt.Sprintf("Permanently delete %d items? (y/n)", len(items))
+}
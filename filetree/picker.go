@@ -0,0 +1,144 @@
+package filetree
+
+import (
+	"path/filepath"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/mistakenelf/teacup/filesystem"
+)
+
+// pickerEntry is a directory shown in a picker along with the key which jumps to it.
+type pickerEntry struct {
+	key       rune
+	directory string
+}
+
+// pickerEntries returns the directories shown by the current picker.
+func (m Model) pickerEntries() []pickerEntry {
+	var entries []pickerEntry
+
+	switch m.inputMode {
+	case bookmarkPickerInput:
+		for _, bookmark := range m.bookmarks.All() {
+			entries = append(entries, pickerEntry{key: bookmark.Mark, directory: bookmark.Directory})
+		}
+	case recentPickerInput:
+		for i, directory := range m.RecentDirectories() {
+			// Only the first nine directories can be jumped to with a key.
+			var key rune
+			if i < 9 {
+				key = '1' + rune(i)
+			}
+
+			entries = append(entries, pickerEntry{key: key, directory: directory})
+		}
+	}
+
+	return entries
+}
+
+// isPicking reports if a picker is shown in place of the listing.
+func (m Model) isPicking() bool {
+	return m.inputMode == bookmarkPickerInput || m.inputMode == recentPickerInput
+}
+
+// startPicker shows a picker in place of the listing.
+func (m *Model) startPicker(mode inputMode) {
+	m.inputMode = mode
+	m.pickerCursor = 0
+	m.updateWindow()
+}
+
+// jumpToDirectory leaves any archives and lists a directory.
+func (m *Model) jumpToDirectory(directory string) tea.Cmd {
+	m.closeArchives()
+
+	return m.getDirectoryListingCmd(directory)
+}
+
+// updatePicker handles key presses while a picker is shown.
+func (m Model) updatePicker(msg tea.KeyMsg) (Model, tea.Cmd) {
+	mode := m.inputMode
+	entries := m.pickerEntries()
+
+	switch msg.Type {
+	case tea.KeyDown, tea.KeyCtrlN:
+		m.pickerCursor = min(m.pickerCursor+1, max(len(entries)-1, 0))
+
+		return m, nil
+	case tea.KeyUp, tea.KeyCtrlP:
+		m.pickerCursor = max(m.pickerCursor-1, 0)
+
+		return m, nil
+	case tea.KeyEnter:
+		m.stopInput()
+
+		if m.pickerCursor < len(entries) {
+			return m, m.jumpToDirectory(entries[m.pickerCursor].directory)
+		}
+
+		return m, nil
+	}
+
+	m.stopInput()
+
+	if msg.Type != tea.KeyRunes || len(msg.Runes) != 1 {
+		return m, nil
+	}
+
+	if mode == bookmarkPickerInput && isBookmarkMark(msg.Runes[0]) {
+		return m, m.jumpToBookmark(msg.Runes[0])
+	}
+
+	for _, entry := range entries {
+		if entry.key != 0 && entry.key == msg.Runes[0] {
+			return m, m.jumpToDirectory(entry.directory)
+		}
+	}
+
+	return m, nil
+}
+
+// pickerView renders the current picker in place of the listing,
+// scrolling to keep the highlighted directory visible.
+func (m Model) pickerView() []string {
+	title, empty := "Bookmarks", "No bookmarks, press m and a letter to add one"
+	if m.inputMode == recentPickerInput {
+		title, empty = "Recent directories", "No recent directories"
+	}
+
+	rows := []string{confirmationStyle.Render(title)}
+
+	entries := m.pickerEntries()
+	if len(entries) == 0 {
+		rows = append(rows, detailsStyle.Render(empty))
+	}
+
+	home, _ := filesystem.GetHomeDirectory()
+	start := max(m.pickerCursor-(m.listHeight()-2), 0)
+
+	for i, entry := range entries[min(start, len(entries)):] {
+		i += start
+
+		// Shorten paths in the home directory in the same way as a shell.
+		directory := entry.directory
+		if home != "" && (directory == home || strings.HasPrefix(directory, home+string(filepath.Separator))) {
+			directory = "~" + strings.TrimPrefix(directory, home)
+		}
+
+		key := " "
+		if entry.key != 0 {
+			key = string(entry.key)
+		}
+
+		row := markedItemStyle.Render(key) + "  " + directory
+		if i == m.pickerCursor {
+			row = selectedItemStyle.Render(key + "  " + directory)
+		}
+
+		rows = append(rows, row)
+	}
+
+	return rows
+}
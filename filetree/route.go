@@ -0,0 +1,65 @@
+package filetree
+
+import (
+	"sync/atomic"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// lastID is the id given to the most recently created model.
+var lastID atomic.Int64
+
+// nextID returns a unique id for a new model.
+func nextID() int {
+	return int(lastID.Add(1))
+}
+
+// routedMsg is a message for the model with the given id, so that
+// several models can be shown side by side in the same program.
+type routedMsg struct {
+	id  int
+	msg tea.Msg
+}
+
+// ID returns the unique id of the model. It is set on the messages sent to
+// the parent app, so that they can be told apart when using several models.
+func (m Model) ID() int {
+	return m.id
+}
+
+// routeCmd tags the messages returned by a command with the id of the model.
+func (m Model) routeCmd(cmd tea.Cmd) tea.Cmd {
+	return routeCmd(m.id, cmd)
+}
+
+// routeCmd tags the messages returned by a command with an id, the
+// commands of a batch are tagged once they are run.
+func routeCmd(id int, cmd tea.Cmd) tea.Cmd {
+	if cmd == nil {
+		return nil
+	}
+
+	return func() tea.Msg {
+		return routeMsg(id, cmd())
+	}
+}
+
+// routeMsg tags the messages of the model with an id. Other messages, such as
+// those for the parent app or used by Bubble Tea itself, are left as they are.
+func routeMsg(id int, msg tea.Msg) tea.Msg {
+	switch msg := msg.(type) {
+	case tea.BatchMsg:
+		cmds := make(tea.BatchMsg, len(msg))
+		for i, cmd := range msg {
+			cmds[i] = routeCmd(id, cmd)
+		}
+
+		return cmds
+	case archiveOpenedMsg, bookmarksLoadedMsg, bookmarkSavedMsg, getDirectoryChildrenMsg,
+		gitStatusMsg, fileOperationMsg, directoryBatchMsg, itemsLoadedMsg, trashListedMsg,
+		trashOperationMsg, watcherStartedMsg, directoryChangedOnDiskMsg, errorMsg:
+		return routedMsg{id: id, msg: msg}
+	}
+
+	return msg
+}
@@ -0,0 +1,96 @@
+package filetree
+
+import (
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// ToggleSelection marks the highlighted item if it is not marked,
+// or unmarks it if it is.
+func (m *Model) ToggleSelection() {
+	if len(m.files) == 0 {
+		return
+	}
+
+	file := m.files[m.cursor]
+	if _, ok := m.selection[file.path]; ok {
+		delete(m.selection, file.path)
+	} else {
+		m.selection[file.path] = file
+	}
+}
+
+// InvertSelection marks every visible item which is not marked and
+// unmarks every visible item which is.
+func (m *Model) InvertSelection() {
+	for _, file := range m.files {
+		if _, ok := m.selection[file.path]; ok {
+			delete(m.selection, file.path)
+		} else {
+			m.selection[file.path] = file
+		}
+	}
+}
+
+// SelectAll marks every visible item.
+func (m *Model) SelectAll() {
+	for _, file := range m.files {
+		m.selection[file.path] = file
+	}
+}
+
+// SelectGlob marks every visible item whose name matches a glob pattern.
+func (m *Model) SelectGlob(pattern string) error {
+	for _, file := range m.files {
+		matched, err := filepath.Match(pattern, file.name)
+		if err != nil {
+			return err
+		}
+
+		if matched {
+			m.selection[file.path] = file
+		}
+	}
+
+	return nil
+}
+
+// ClearSelection unmarks every item, including items in other directories.
+func (m *Model) ClearSelection() {
+	clear(m.selection)
+}
+
+// IsSelected returns if the item at the given path is marked.
+func (m Model) IsSelected(path string) bool {
+	_, ok := m.selection[path]
+
+	return ok
+}
+
+// SelectedItems returns the marked items ordered by path. Marks are kept while
+// moving between directories so items may come from several directories.
+func (m Model) SelectedItems() []DirectoryItem {
+	items := make([]DirectoryItem, 0, len(m.selection))
+	for _, item := range m.selection {
+		items = append(items, item)
+	}
+
+	slices.SortFunc(items, func(a, b DirectoryItem) int {
+		return strings.Compare(a.path, b.path)
+	})
+
+	return items
+}
+
+// SelectedPaths returns the paths of the marked items, ordered by path.
+func (m Model) SelectedPaths() []string {
+	items := m.SelectedItems()
+	paths := make([]string, 0, len(items))
+
+	for _, item := range items {
+		paths = append(paths, item.path)
+	}
+
+	return paths
+}
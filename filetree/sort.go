@@ -0,0 +1,143 @@
+package filetree
+
+import (
+	"cmp"
+	"os"
+	"slices"
+	"strings"
+	"unicode"
+)
+
+// compareNatural compares two strings treating runs of digits as numbers,
+// so that file2 is ordered before file10.
+func compareNatural(a, b string) int {
+	ar, br := []rune(a), []rune(b)
+	i, j := 0, 0
+
+	for i < len(ar) && j < len(br) {
+		if unicode.IsDigit(ar[i]) && unicode.IsDigit(br[j]) {
+			startA, startB := i, j
+			for i < len(ar) && unicode.IsDigit(ar[i]) {
+				i++
+			}
+
+			for j < len(br) && unicode.IsDigit(br[j]) {
+				j++
+			}
+
+			numberA := strings.TrimLeft(string(ar[startA:i]), "0")
+			numberB := strings.TrimLeft(string(br[startB:j]), "0")
+
+			// A longer number without leading zeros is always larger.
+			if c := cmp.Compare(len(numberA), len(numberB)); c != 0 {
+				return c
+			}
+
+			if c := cmp.Compare(numberA, numberB); c != 0 {
+				return c
+			}
+
+			continue
+		}
+
+		if c := cmp.Compare(unicode.ToLower(ar[i]), unicode.ToLower(br[j])); c != 0 {
+			return c
+		}
+
+		i++
+		j++
+	}
+
+	return cmp.Compare(len(ar)-i, len(br)-j)
+}
+
+// typeRank orders items by their kind when sorting by type.
+func typeRank(file DirectoryItem) int {
+	switch {
+	case file.isDirectory:
+		return 0
+	case file.mode&os.ModeSymlink != 0:
+		return 1
+	case !file.mode.IsRegular():
+		return 2
+	default:
+		return 3
+	}
+}
+
+// compareItems compares two items based on the current sort settings.
+func (m Model) compareItems(a, b DirectoryItem) int {
+	if m.directoriesFirst && a.isDirectory != b.isDirectory {
+		if a.isDirectory {
+			return -1
+		}
+
+		return 1
+	}
+
+	byName := func() int {
+		if m.naturalSort {
+			return compareNatural(a.name, b.name)
+		}
+
+		return cmp.Or(cmp.Compare(strings.ToLower(a.name), strings.ToLower(b.name)), cmp.Compare(a.name, b.name))
+	}
+
+	var c int
+
+	switch m.sortBy {
+	case SortByName:
+		c = byName()
+	case SortBySize:
+		c = cmp.Or(cmp.Compare(a.size, b.size), byName())
+	case SortByModTime:
+		c = cmp.Or(a.modTime.Compare(b.modTime), byName())
+	case SortByExtension:
+		c = cmp.Or(cmp.Compare(strings.ToLower(a.extension), strings.ToLower(b.extension)), byName())
+	case SortByType:
+		c = cmp.Or(cmp.Compare(typeRank(a), typeRank(b)), cmp.Compare(strings.ToLower(a.extension), strings.ToLower(b.extension)), byName())
+	}
+
+	if m.sortOrder == Descending {
+		return -c
+	}
+
+	return c
+}
+
+// sortFiles sorts the items within each directory, keeping the
+// children of expanded directories directly below them.
+func (m Model) sortFiles(files []DirectoryItem) []DirectoryItem {
+	if len(files) == 0 {
+		return files
+	}
+
+	children := make(map[string][]DirectoryItem)
+	for _, file := range files {
+		children[file.parent] = append(children[file.parent], file)
+	}
+
+	for _, siblings := range children {
+		slices.SortStableFunc(siblings, m.compareItems)
+	}
+
+	sorted := make([]DirectoryItem, 0, len(files))
+
+	var appendChildren func(parent string)
+	appendChildren = func(parent string) {
+		for _, file := range children[parent] {
+			sorted = append(sorted, file)
+			appendChildren(file.path)
+		}
+	}
+
+	appendChildren(files[0].parent)
+
+	return sorted
+}
+
+// resort sorts the current listing, keeping the cursor on the same item.
+func (m *Model) resort() {
+	m.allFiles = layoutTree(m.sortFiles(m.allFiles))
+	m.applyFilter()
+}
@@ -0,0 +1,383 @@
+package filetree
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"slices"
+	"sync"
+	"sync/atomic"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/mistakenelf/teacup/filesystem"
+)
+
+const (
+	// firstBatchSize is the number of entries read before a directory is first
+	// shown, kept small so that the listing appears quickly.
+	firstBatchSize = 256
+
+	// batchSize is the number of entries read at a time after the first batch.
+	batchSize = 4096
+)
+
+// directoryStream reads a directory in batches so that large
+// directories are listed without blocking the bubble.
+type directoryStream struct {
+	fsys      filesystem.FS
+	directory string
+	file      fs.ReadDirFile
+	options   listingOptions
+	ignorer   *filesystem.Ignorer
+	cancelled atomic.Bool
+	closeOnce sync.Once
+}
+
+type directoryBatchMsg struct {
+	stream *directoryStream
+	files  []DirectoryItem
+	first  bool
+	done   bool
+	err    error
+}
+
+type itemsLoadedMsg struct {
+	directory string
+	infos     map[string]itemInfo
+}
+
+// openDirectoryStream opens a directory and reads its first batch of items.
+// Directories with expanded children in tree mode are read all at once.
+func openDirectoryStream(fsys filesystem.FS, directoryName string, options listingOptions) tea.Msg {
+	stream := &directoryStream{
+		fsys:      fsys,
+		directory: directoryName,
+		options:   options,
+		ignorer:   options.ignorer(fsys, directoryName),
+	}
+
+	if len(options.expanded) > 0 {
+		files, err := readExpandedDirectoryItems(fsys, directoryName, 0, options, stream.ignorer)
+
+		return directoryBatchMsg{stream: stream, files: files, first: true, done: true, err: err}
+	}
+
+	file, err := fsys.Open(directoryName)
+	if err != nil {
+		return errorMsg(errors.Unwrap(err))
+	}
+
+	// Backends which can not read a directory in batches read it all at once.
+	if readDirFile, ok := file.(fs.ReadDirFile); ok {
+		stream.file = readDirFile
+	} else {
+		_ = file.Close()
+	}
+
+	msg := stream.readBatch(firstBatchSize)
+	msg.first = true
+
+	return msg
+}
+
+// readBatch reads up to n entries of the directory.
+func (s *directoryStream) readBatch(n int) directoryBatchMsg {
+	var (
+		entries []fs.DirEntry
+		err     error
+		done    bool
+	)
+
+	if s.file == nil {
+		entries, err = s.fsys.ReadDir(s.directory)
+		done = true
+	} else {
+		entries, err = s.file.ReadDir(n)
+		if errors.Is(err, io.EOF) {
+			err = nil
+			done = true
+		}
+	}
+
+	if done || err != nil {
+		s.Close()
+	}
+
+	return directoryBatchMsg{
+		stream: s,
+		files:  newDirectoryItems(s.fsys, s.directory, entries, 0, s.options, s.ignorer),
+		done:   done,
+		err:    errors.Unwrap(err),
+	}
+}
+
+// Close stops reading the directory.
+func (s *directoryStream) Close() {
+	s.closeOnce.Do(func() {
+		if s.file != nil {
+			_ = s.file.Close()
+		}
+	})
+}
+
+// readBatchCmd reads the next batch of a directory unless it was cancelled.
+func readBatchCmd(stream *directoryStream) tea.Cmd {
+	return func() tea.Msg {
+		if stream.cancelled.Load() {
+			stream.Close()
+
+			return nil
+		}
+
+		return stream.readBatch(batchSize)
+	}
+}
+
+// loadItemsCmd reads the details of items in the background.
+func loadItemsCmd(fsys filesystem.FS, directory string, paths []string) tea.Cmd {
+	return func() tea.Msg {
+		infos := make(map[string]itemInfo, len(paths))
+		for _, path := range paths {
+			infos[path] = statItem(fsys, path)
+		}
+
+		return itemsLoadedMsg{directory: directory, infos: infos}
+	}
+}
+
+// sortNeedsInfo reports if items can only be sorted once their details are loaded.
+func (m Model) sortNeedsInfo() bool {
+	return m.sortBy == SortBySize || m.sortBy == SortByModTime
+}
+
+// loadItemsCmd loads the details of the visible items, or of every item
+// when they are needed for sorting.
+func (m *Model) loadItemsCmd() tea.Cmd {
+	candidates := m.allFiles
+	if !m.sortNeedsInfo() {
+		candidates = m.visibleFiles()
+	}
+
+	var paths []string
+
+	for _, file := range candidates {
+		if !file.loaded && !m.loadingItems[file.path] {
+			m.loadingItems[file.path] = true
+			paths = append(paths, file.path)
+		}
+	}
+
+	if len(paths) == 0 {
+		return nil
+	}
+
+	return loadItemsCmd(m.fsys, m.currentDirectory, paths)
+}
+
+// handleItemsLoaded fills in the details of items which were loaded.
+func (m *Model) handleItemsLoaded(msg itemsLoadedMsg) {
+	for path := range msg.infos {
+		delete(m.loadingItems, path)
+	}
+
+	if msg.directory != m.currentDirectory {
+		return
+	}
+
+	for _, files := range [][]DirectoryItem{m.allFiles, m.files} {
+		for i := range files {
+			if info, ok := msg.infos[files[i].path]; ok && !files[i].loaded {
+				files[i].loadInfo(info)
+			}
+		}
+	}
+
+	if m.sortNeedsInfo() {
+		m.resort()
+	}
+}
+
+// mergeFiles merges a batch of items into sorted items.
+func (m Model) mergeFiles(files, batch []DirectoryItem) []DirectoryItem {
+	// Children of directories expanded while loading are kept below them by sortFiles.
+	if len(files) == 0 || (m.treeMode && len(m.expanded) > 0) {
+		return m.sortFiles(append(files, batch...))
+	}
+
+	slices.SortStableFunc(batch, m.compareItems)
+
+	merged := make([]DirectoryItem, 0, len(files)+len(batch))
+
+	for len(files) > 0 && len(batch) > 0 {
+		if m.compareItems(batch[0], files[0]) < 0 {
+			merged = append(merged, batch[0])
+			batch = batch[1:]
+		} else {
+			merged = append(merged, files[0])
+			files = files[1:]
+		}
+	}
+
+	merged = append(merged, files...)
+
+	return append(merged, batch...)
+}
+
+// handleDirectoryBatch adds a batch of items to the listing. A new directory is shown
+// as it is read, while the current directory is replaced once it has been read again.
+func (m *Model) handleDirectoryBatch(msg directoryBatchMsg) tea.Cmd {
+	if msg.first {
+		m.CancelLoading()
+		m.stream = msg.stream
+		m.streamFiles = nil
+		m.streamCount = 0
+		m.streamReplace = msg.stream.directory == m.currentDirectory
+		m.updateWindow()
+	} else if msg.stream != m.stream {
+		msg.stream.Close()
+
+		return nil
+	}
+
+	if msg.err != nil {
+		m.CancelLoading()
+		m.setError(msg.err)
+
+		return nil
+	}
+
+	var cmds []tea.Cmd
+
+	m.streamCount += len(msg.files)
+
+	switch {
+	case m.streamReplace:
+		m.streamFiles = m.mergeFiles(m.streamFiles, msg.files)
+		if msg.done {
+			m.replaceListing(m.streamFiles)
+		}
+	case msg.first:
+		cmds = append(cmds, m.beginListing(msg.stream.directory, m.mergeFiles(nil, msg.files)))
+	default:
+		// The cursor follows the highlighted item once it has been moved,
+		// otherwise it stays at the top as earlier items are read.
+		atTop := m.cursor == 0
+
+		m.allFiles = layoutTree(m.mergeFiles(m.allFiles, msg.files))
+		m.applyFilter()
+
+		if atTop {
+			m.setCursor(0)
+		}
+	}
+
+	if !m.streamReplace || msg.done {
+		m.selectPendingItem()
+	}
+
+	if !msg.done {
+		return tea.Batch(append(cmds, readBatchCmd(msg.stream))...)
+	}
+
+	m.stream = nil
+	m.streamFiles = nil
+	m.returnPath = ""
+	m.pendingHistory = nil
+	m.updateWindow()
+
+	// Git status is read after every listing so that it follows changes on disk.
+	cmds = append(cmds, m.gitStatusCmd())
+
+	return tea.Batch(cmds...)
+}
+
+// beginListing shows the first items of a directory which was not listed before.
+func (m *Model) beginListing(directory string, files []DirectoryItem) tea.Cmd {
+	var cmd tea.Cmd
+
+	// Filters only apply to the directory they were typed in.
+	m.filter = ""
+	m.filterType = ""
+	m.stopInput()
+
+	// Directories from the history were already moved between
+	// the back and forward history when they were gone to.
+	if m.pendingHistory == nil || m.pendingHistory.Directory != directory {
+		m.pushHistory()
+	}
+
+	m.allFiles = layoutTree(files)
+	m.currentDirectory = directory
+	m.files = m.allFiles
+	clear(m.loadingItems)
+	m.resetCursor()
+	m.addRecentDirectory(directory)
+
+	// Stop watching the previous directory and start watching the new one.
+	m.stopWatching()
+	if m.watch && filesystem.IsOS(m.fsys) {
+		cmd = watchDirectoryCmd(directory)
+	}
+
+	return cmd
+}
+
+// replaceListing replaces the items of the current directory after it was read again.
+func (m *Model) replaceListing(files []DirectoryItem) {
+	m.allFiles = layoutTree(files)
+	m.files = filterFiles(m.allFiles, m.filter, m.filterType)
+	m.setCursor(m.cursor)
+}
+
+// selectPendingItem moves the cursor to where it was in a directory from
+// the history, or to the directory which was just left, once it is listed.
+func (m *Model) selectPendingItem() {
+	if m.pendingHistory != nil && m.pendingHistory.Directory == m.currentDirectory {
+		m.restoreHistory(*m.pendingHistory)
+
+		if len(m.files) > m.pendingHistory.Cursor {
+			m.pendingHistory = nil
+		}
+	}
+
+	if m.returnPath == "" {
+		return
+	}
+
+	for i, file := range m.files {
+		if file.path == m.returnPath {
+			m.setCursor(i)
+			m.returnPath = ""
+
+			break
+		}
+	}
+}
+
+// IsLoading reports if a directory is still being read.
+func (m Model) IsLoading() bool {
+	return m.stream != nil
+}
+
+// CancelLoading stops reading the current directory, keeping the items read so far.
+func (m *Model) CancelLoading() {
+	if m.stream == nil {
+		return
+	}
+
+	// The batch being read, if any, closes the stream once it is received.
+	m.stream.cancelled.Store(true)
+	m.stream = nil
+	m.streamFiles = nil
+	m.updateWindow()
+}
+
+// loadingStatus returns the text shown below the listing while a directory is read.
+func (m Model) loadingStatus() string {
+	if m.stream == nil {
+		return ""
+	}
+
+	return detailsStyle.Render(fmt.Sprintf("Loading… %d entries", m.streamCount))
+}
@@ -4,4 +4,18 @@ import "github.com/charmbracelet/lipgloss"
 
 var (
 	selectedItemStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("212")).Bold(true)
+	treeGuideStyle    = lipgloss.NewStyle().Foreground(lipgloss.Color("240"))
+	detailsStyle      = lipgloss.NewStyle().Foreground(lipgloss.Color("245"))
+	markedItemStyle   = lipgloss.NewStyle().Foreground(lipgloss.Color("214"))
+	errorStyle        = lipgloss.NewStyle().Foreground(lipgloss.Color("196"))
+	confirmationStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("214")).Bold(true)
+	filterMatchStyle  = lipgloss.NewStyle().Foreground(lipgloss.Color("42")).Underline(true)
+	linkTargetStyle   = lipgloss.NewStyle().Foreground(lipgloss.Color("245"))
+	brokenLinkStyle   = lipgloss.NewStyle().Foreground(lipgloss.Color("203")).Strikethrough(true)
+
+	gitIgnoredStyle    = lipgloss.NewStyle().Foreground(lipgloss.Color("240"))
+	gitUntrackedStyle  = lipgloss.NewStyle().Foreground(lipgloss.Color("39"))
+	gitStagedStyle     = lipgloss.NewStyle().Foreground(lipgloss.Color("42"))
+	gitModifiedStyle   = lipgloss.NewStyle().Foreground(lipgloss.Color("220"))
+	gitConflictedStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("196"))
 )
@@ -0,0 +1,230 @@
+package filetree
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/mistakenelf/teacup/filesystem"
+)
+
+type trashListedMsg struct {
+	items []filesystem.TrashItem
+	err   error
+}
+
+type trashOperationMsg struct {
+	err error
+}
+
+// listTrashCmd reads the items in the trash.
+func listTrashCmd() tea.Msg {
+	items, err := filesystem.ListTrash()
+
+	return trashListedMsg{items: items, err: err}
+}
+
+// trashOperationCmd runs an operation on the trash, listing it again once done.
+func trashOperationCmd(operation func() error) tea.Cmd {
+	return func() tea.Msg {
+		return trashOperationMsg{err: operation()}
+	}
+}
+
+// canTrash reports if items can be moved to the trash, which is only
+// available for the local disk.
+func (m Model) canTrash() bool {
+	return len(m.archives) == 0 && filesystem.IsOS(m.fsys)
+}
+
+// trashCmd moves the target items to the trash.
+func (m Model) trashCmd() tea.Cmd {
+	items := m.targetItems()
+
+	return m.journaledCmd(func(batch *filesystem.Batch) error {
+		for _, item := range items {
+			if err := batch.Trash(item.path); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}, "")
+}
+
+// isTrashView reports if the trash is shown in place of the listing.
+func (m Model) isTrashView() bool {
+	switch m.inputMode {
+	case trashViewInput, confirmTrashDeleteInput, confirmEmptyTrashInput:
+		return true
+	}
+
+	return false
+}
+
+// ShowTrash shows the items in the trash in place of the listing, where
+// they can be restored or permanently deleted.
+func (m *Model) ShowTrash() tea.Cmd {
+	m.inputMode = trashViewInput
+	m.trashItems = nil
+	m.trashCursor = 0
+	m.updateWindow()
+
+	return m.routeCmd(listTrashCmd)
+}
+
+// selectedTrashItem returns the highlighted item in the trash.
+func (m Model) selectedTrashItem() (filesystem.TrashItem, bool) {
+	if m.trashCursor >= len(m.trashItems) {
+		return filesystem.TrashItem{}, false
+	}
+
+	return m.trashItems[m.trashCursor], true
+}
+
+// updateTrashView handles key presses while the trash is shown.
+func (m Model) updateTrashView(msg tea.KeyMsg) (Model, tea.Cmd) {
+	item, hasItem := m.selectedTrashItem()
+
+	// Anything other than confirming goes back to the trash.
+	switch m.inputMode {
+	case confirmTrashDeleteInput:
+		m.inputMode = trashViewInput
+		m.updateWindow()
+
+		if key.Matches(msg, m.keyMap.Confirm) && hasItem {
+			return m, trashOperationCmd(func() error {
+				return filesystem.RemoveFromTrash(item)
+			})
+		}
+
+		return m, nil
+	case confirmEmptyTrashInput:
+		m.inputMode = trashViewInput
+		m.updateWindow()
+
+		if key.Matches(msg, m.keyMap.Confirm) {
+			return m, trashOperationCmd(filesystem.EmptyTrash)
+		}
+
+		return m, nil
+	}
+
+	// Errors are shown until the next key press.
+	if m.err != nil {
+		m.setError(nil)
+	}
+
+	switch {
+	case key.Matches(msg, m.keyMap.Down):
+		m.trashCursor = min(m.trashCursor+1, max(len(m.trashItems)-1, 0))
+	case key.Matches(msg, m.keyMap.Up):
+		m.trashCursor = max(m.trashCursor-1, 0)
+	case key.Matches(msg, m.keyMap.Top):
+		m.trashCursor = 0
+	case key.Matches(msg, m.keyMap.Bottom):
+		m.trashCursor = max(len(m.trashItems)-1, 0)
+	case key.Matches(msg, m.keyMap.Restore, m.keyMap.Submit):
+		if hasItem {
+			return m, trashOperationCmd(func() error {
+				return filesystem.RestoreTrash(item)
+			})
+		}
+	case key.Matches(msg, m.keyMap.Delete):
+		if hasItem {
+			m.inputMode = confirmTrashDeleteInput
+			m.updateWindow()
+		}
+	case key.Matches(msg, m.keyMap.EmptyTrash):
+		if len(m.trashItems) > 0 {
+			m.inputMode = confirmEmptyTrashInput
+			m.updateWindow()
+		}
+	case key.Matches(msg, m.keyMap.Cancel, m.keyMap.ShowTrash):
+		m.stopInput()
+	}
+
+	return m, nil
+}
+
+// handleTrashListed shows the items read from the trash.
+func (m *Model) handleTrashListed(msg trashListedMsg) {
+	m.trashItems = msg.items
+	m.trashCursor = min(m.trashCursor, max(len(m.trashItems)-1, 0))
+	m.setError(msg.err)
+}
+
+// handleTrashOperation lists the trash again after it changed, along with the
+// current directory as items may have been restored to it.
+func (m *Model) handleTrashOperation(msg trashOperationMsg) tea.Cmd {
+	m.setError(msg.err)
+
+	cmds := []tea.Cmd{m.refresh("")}
+	if m.isTrashView() {
+		cmds = append(cmds, listTrashCmd)
+	}
+
+	return tea.Batch(cmds...)
+}
+
+// trashPrompt returns the footer shown while the trash is shown.
+func (m Model) trashPrompt() string {
+	switch m.inputMode {
+	case confirmTrashDeleteInput:
+		item, _ := m.selectedTrashItem()
+
+		return confirmationStyle.Render(fmt.Sprintf("Permanently delete %s? (y/n)", filepath.Base(item.OriginalPath)))
+	case confirmEmptyTrashInput:
+		return confirmationStyle.Render(fmt.Sprintf("Permanently delete %d items in the trash? (y/n)", len(m.trashItems)))
+	}
+
+	keyMap := m.activeKeyMap()
+
+	var actions []string
+
+	for _, binding := range []key.Binding{keyMap.Restore, keyMap.Delete, keyMap.EmptyTrash, keyMap.Cancel} {
+		if binding.Enabled() {
+			actions = append(actions, fmt.Sprintf("%s to %s", binding.Help().Key, binding.Help().Desc))
+		}
+	}
+
+	return detailsStyle.Render(strings.Join(actions, ", "))
+}
+
+// trashView renders the trash in place of the listing,
+// scrolling to keep the highlighted item visible.
+func (m Model) trashView() []string {
+	rows := []string{confirmationStyle.Render("Trash")}
+	if len(m.trashItems) == 0 {
+		rows = append(rows, detailsStyle.Render("The trash is empty"))
+	}
+
+	home, _ := filesystem.GetHomeDirectory()
+	start := max(m.trashCursor-(m.listHeight()-2), 0)
+
+	for i, item := range m.trashItems[min(start, len(m.trashItems)):] {
+		i += start
+
+		// Shorten paths in the home directory in the same way as a shell.
+		path := item.OriginalPath
+		if home != "" && strings.HasPrefix(path, home+string(filepath.Separator)) {
+			path = "~" + strings.TrimPrefix(path, home)
+		}
+
+		date := "-"
+		if !item.DeletionDate.IsZero() {
+			date = item.DeletionDate.Format(m.timeFormat)
+		}
+
+		row := detailsStyle.Render(date) + "  " + path
+		if i == m.trashCursor {
+			row = selectedItemStyle.Render(date + "  " + path)
+		}
+
+		rows = append(rows, row)
+	}
+
+	return rows
+}
@@ -1,46 +1,346 @@
 package filetree
 
 import (
+	"path/filepath"
+	"strings"
+
 	"github.com/charmbracelet/bubbles/key"
 	tea "github.com/charmbracelet/bubbletea"
+	"github.com/mistakenelf/teacup/filesystem"
 )
 
+// updateInput handles key presses while the text input is focused.
+func (m Model) updateInput(msg tea.KeyMsg) (Model, tea.Cmd) {
+	var cmd tea.Cmd
+
+	switch m.inputMode {
+	case confirmDeleteInput:
+		if key.Matches(msg, m.keyMap.Confirm) {
+			cmd = m.deleteCmd()
+			m.ClearSelection()
+		}
+
+		m.stopInput()
+
+		return m, cmd
+	case selectGlobInput, createFileInput, createDirectoryInput, renameInput:
+		switch {
+		case key.Matches(msg, m.keyMap.Cancel):
+			m.stopInput()
+		case key.Matches(msg, m.keyMap.Submit):
+			cmd = m.submitInput()
+		default:
+			m.input, cmd = m.input.Update(msg)
+		}
+
+		return m, cmd
+	}
+
+	switch {
+	case key.Matches(msg, m.keyMap.Cancel):
+		m.clearFilter()
+	case key.Matches(msg, m.keyMap.Submit):
+		m.stopInput()
+	case key.Matches(msg, m.keyMap.FilterType):
+		switch m.filterType {
+		case "":
+			m.SetFilterType(filesystem.FilesListingType)
+		case filesystem.FilesListingType:
+			m.SetFilterType(filesystem.DirectoriesListingType)
+		default:
+			m.SetFilterType("")
+		}
+	case msg.Type == tea.KeyDown || msg.Type == tea.KeyCtrlN:
+		m.setCursor(m.cursor + 1)
+	case msg.Type == tea.KeyUp || msg.Type == tea.KeyCtrlP:
+		m.setCursor(m.cursor - 1)
+	default:
+		m.input, cmd = m.input.Update(msg)
+
+		if m.input.Value() != m.filter {
+			m.filter = m.input.Value()
+			m.applyFilter()
+		}
+	}
+
+	return m, cmd
+}
+
+// submitInput runs the action for the current input mode with the entered value.
+func (m *Model) submitInput() tea.Cmd {
+	var cmd tea.Cmd
+
+	value := m.input.Value()
+	if strings.TrimSpace(value) == "" {
+		m.stopInput()
+
+		return nil
+	}
+
+	switch m.inputMode {
+	case selectGlobInput:
+		m.err = m.SelectGlob(value)
+	case createFileInput:
+		cmd = m.createFileCmd(value)
+	case createDirectoryInput:
+		cmd = m.createDirectoryCmd(value)
+	case renameInput:
+		cmd = m.renameCmd(value)
+	}
+
+	m.stopInput()
+
+	return cmd
+}
+
 func (m Model) Update(msg tea.Msg) (Model, tea.Cmd) {
+	// Messages of other models are ignored.
+	if routed, ok := msg.(routedMsg); ok {
+		if routed.id != m.id {
+			return m, nil
+		}
+
+		msg = routed.msg
+	}
+
+	previousDirectory := m.currentDirectory
+	previousItem, _ := m.SelectedItem()
+
+	m, cmd := m.update(msg)
+
+	// Details are only loaded for items once they are scrolled into view.
+	return m, m.routeCmd(tea.Batch(cmd, m.loadItemsCmd(), m.notifyCmd(previousDirectory, previousItem)))
+}
+
+// update handles a message, the parent app is told what changed by Update.
+func (m Model) update(msg tea.Msg) (Model, tea.Cmd) {
 	var (
 		cmds []tea.Cmd
 	)
 
 	switch msg := msg.(type) {
 	case tea.WindowSizeMsg:
-		m.height = msg.Height
-		m.width = msg.Width
-		m.max = m.height - 1
-	case getDirectoryListingMsg:
-		if msg != nil {
-			m.files = msg
-			m.max = max(m.max, m.height-1)
+		m.SetSize(msg.Width, msg.Height)
+	case directoryBatchMsg:
+		cmds = append(cmds, m.handleDirectoryBatch(msg))
+	case itemsLoadedMsg:
+		m.handleItemsLoaded(msg)
+	case gitStatusMsg:
+		if msg.directory == m.currentDirectory {
+			m.gitStatuses = msg.statuses
+		}
+	case watcherStartedMsg:
+		if !m.watch || msg.watcher.directory != m.currentDirectory {
+			msg.watcher.Close()
+
+			break
 		}
+
+		m.stopWatching()
+		m.watcher = msg.watcher
+		cmds = append(cmds, waitForChangeCmd(m.watcher))
+	case directoryChangedOnDiskMsg:
+		if msg.watcher != m.watcher {
+			break
+		}
+
+		if len(m.files) > 0 {
+			cmds = append(cmds, m.refresh(m.files[m.cursor].path))
+		} else {
+			cmds = append(cmds, m.refresh(""))
+		}
+
+		cmds = append(cmds, waitForChangeCmd(m.watcher))
+	case fileOperationMsg:
+		m.setError(msg.err)
+		cmds = append(cmds, m.refresh(msg.selectPath))
+	case archiveOpenedMsg:
+		cmds = append(cmds, m.enterArchive(msg))
+	case trashListedMsg:
+		m.handleTrashListed(msg)
+	case trashOperationMsg:
+		cmds = append(cmds, m.handleTrashOperation(msg))
+	case bookmarksLoadedMsg:
+		m.setError(msg.err)
+	case bookmarkSavedMsg:
+		if msg.err != nil {
+			m.setError(msg.err)
+		}
+	case errorMsg:
+		m.setError(msg)
+	case getDirectoryChildrenMsg:
+		for i, file := range m.allFiles {
+			// Ignore children of directories which were collapsed or
+			// already loaded before the listing finished.
+			if file.path != msg.parent || !m.expanded[file.path] || descendantsEnd(m.allFiles, i) > i+1 {
+				continue
+			}
+
+			files := make([]DirectoryItem, 0, len(m.allFiles)+len(msg.files))
+			files = append(files, m.allFiles[:i+1]...)
+			files = append(files, msg.files...)
+			files = append(files, m.allFiles[i+1:]...)
+			m.allFiles = layoutTree(m.sortFiles(files))
+			m.applyFilter()
+
+			break
+		}
+	case tea.MouseMsg:
+		cmds = append(cmds, m.handleMouse(msg))
 	case tea.KeyMsg:
+		if m.inputMode == setBookmarkInput {
+			return m.updateBookmarkInput(msg)
+		}
+
+		if m.isPicking() {
+			return m.updatePicker(msg)
+		}
+
+		if m.isTrashView() {
+			return m.updateTrashView(msg)
+		}
+
+		if m.inputMode != noInput {
+			return m.updateInput(msg)
+		}
+
+		// Errors are shown until the next key press.
+		if m.err != nil {
+			m.setError(nil)
+		}
+
 		switch {
 		case key.Matches(msg, m.keyMap.Down):
-			m.cursor++
-			if m.cursor >= len(m.files) {
-				m.cursor = len(m.files) - 1
-			}
+			m.setCursor(m.cursor + 1)
+		case key.Matches(msg, m.keyMap.Up):
+			m.setCursor(m.cursor - 1)
+		case key.Matches(msg, m.keyMap.PageDown):
+			m.setCursor(m.cursor + max(m.listHeight(), 1))
+		case key.Matches(msg, m.keyMap.PageUp):
+			m.setCursor(m.cursor - max(m.listHeight(), 1))
+		case key.Matches(msg, m.keyMap.Top):
+			m.setCursor(0)
+		case key.Matches(msg, m.keyMap.Bottom):
+			m.setCursor(len(m.files) - 1)
+		case key.Matches(msg, m.keyMap.Open):
+			cmds = append(cmds, m.openSelectedItem())
+		case key.Matches(msg, m.keyMap.Back):
+			// In tree mode, collapse the directory containing the
+			// current item before leaving the directory.
+			if m.treeMode && len(m.files) > 0 && m.files[m.cursor].depth > 0 {
+				for i := m.cursor; i >= 0; i-- {
+					if m.files[i].path == m.files[m.cursor].parent {
+						m.setCursor(i)
+						cmds = append(cmds, m.toggleDirectory(m.files[i]))
+
+						break
+					}
+				}
 
-			if m.cursor > m.max {
-				m.min++
-				m.max++
+				break
 			}
-		case key.Matches(msg, m.keyMap.Up):
-			m.cursor--
-			if m.cursor < 0 {
-				m.cursor = 0
+
+			// Going back from the root of an archive returns to the directory containing it.
+			if len(m.archives) > 0 && filepath.Dir(m.currentDirectory) == m.currentDirectory {
+				cmds = append(cmds, m.leaveArchive())
+
+				break
 			}
 
-			if m.cursor < m.min {
-				m.min--
-				m.max--
+			m.returnPath = m.currentDirectory
+			cmds = append(cmds, m.getDirectoryListingCmd(filesystem.PreviousDirectory))
+		case key.Matches(msg, m.keyMap.Home):
+			m.closeArchives()
+			cmds = append(cmds, m.getDirectoryListingCmd(filesystem.HomeDirectory))
+		case key.Matches(msg, m.keyMap.Root):
+			cmds = append(cmds, m.getDirectoryListingCmd(filesystem.RootDirectory))
+		case key.Matches(msg, m.keyMap.Tree):
+			cmds = append(cmds, m.SetTreeMode(!m.treeMode))
+		case key.Matches(msg, m.keyMap.Details):
+			m.SetShowDetails(!m.showDetails)
+		case key.Matches(msg, m.keyMap.ToggleHidden):
+			cmds = append(cmds, m.SetShowHidden(!m.showHidden))
+		case key.Matches(msg, m.keyMap.ToggleIgnored):
+			cmds = append(cmds, m.SetShowIgnored(!m.showIgnored))
+		case key.Matches(msg, m.keyMap.Sort):
+			m.SetSortBy((m.sortBy + 1) % (SortByType + 1))
+		case key.Matches(msg, m.keyMap.SortOrder):
+			m.SetSortOrder((m.sortOrder + 1) % (Descending + 1))
+		case key.Matches(msg, m.keyMap.Filter):
+			cmds = append(cmds, m.startFiltering())
+		case key.Matches(msg, m.keyMap.Cancel) && m.IsLoading():
+			m.CancelLoading()
+		case key.Matches(msg, m.keyMap.Cancel) && m.filterStatus() != "":
+			m.clearFilter()
+		case key.Matches(msg, m.keyMap.ClearSelection):
+			m.ClearSelection()
+		case key.Matches(msg, m.keyMap.Select):
+			m.ToggleSelection()
+			m.setCursor(m.cursor + 1)
+		case key.Matches(msg, m.keyMap.InvertSelection):
+			m.InvertSelection()
+		case key.Matches(msg, m.keyMap.SelectAll):
+			m.SelectAll()
+		case key.Matches(msg, m.keyMap.SelectGlob):
+			cmds = append(cmds, m.startInput(selectGlobInput, "select: ", ""))
+		case key.Matches(msg, m.keyMap.CreateFile):
+			cmds = append(cmds, m.startInput(createFileInput, "new file: ", ""))
+		case key.Matches(msg, m.keyMap.CreateDirectory):
+			cmds = append(cmds, m.startInput(createDirectoryInput, "new directory: ", ""))
+		case key.Matches(msg, m.keyMap.Rename):
+			if len(m.files) > 0 {
+				cmds = append(cmds, m.startInput(renameInput, "rename: ", m.files[m.cursor].name))
+			}
+		case key.Matches(msg, m.keyMap.Trash):
+			if m.canTrash() && len(m.targetItems()) > 0 {
+				cmds = append(cmds, m.trashCmd())
+				m.ClearSelection()
+			}
+		case key.Matches(msg, m.keyMap.Delete):
+			if len(m.targetItems()) > 0 {
+				m.startConfirmation()
+			}
+		case key.Matches(msg, m.keyMap.Undo):
+			if m.journal.Len() > 0 {
+				cmds = append(cmds, m.undoCmd())
+			}
+		case key.Matches(msg, m.keyMap.ShowTrash):
+			if m.canTrash() {
+				cmds = append(cmds, m.ShowTrash())
+			}
+		case key.Matches(msg, m.keyMap.Copy):
+			if len(m.files) > 0 {
+				cmds = append(cmds, m.copyCmd())
+				m.ClearSelection()
+			}
+		case key.Matches(msg, m.keyMap.Zip):
+			if len(m.files) > 0 {
+				cmds = append(cmds, m.zipCmd())
+				m.ClearSelection()
+			}
+		case key.Matches(msg, m.keyMap.Unzip):
+			if len(m.files) > 0 && !m.files[m.cursor].isDirectory {
+				cmds = append(cmds, m.unzipCmd())
+			}
+		case key.Matches(msg, m.keyMap.SetBookmark):
+			if m.bookmarks != nil && m.currentDirectory != "" {
+				m.inputMode = setBookmarkInput
+				m.updateWindow()
+			}
+		case key.Matches(msg, m.keyMap.JumpToBookmark):
+			if m.bookmarks != nil {
+				m.startPicker(bookmarkPickerInput)
+			}
+		case key.Matches(msg, m.keyMap.HistoryBack):
+			cmds = append(cmds, m.GoBack())
+		case key.Matches(msg, m.keyMap.HistoryForward):
+			cmds = append(cmds, m.GoForward())
+		case key.Matches(msg, m.keyMap.Recent):
+			m.startPicker(recentPickerInput)
+		case key.Matches(msg, m.keyMap.Extract):
+			if len(m.archives) > 0 && len(m.files) > 0 {
+				cmds = append(cmds, m.extractCmd())
+				m.ClearSelection()
 			}
 		}
 	}
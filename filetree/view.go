@@ -1,31 +1,245 @@
 package filetree
 
 import (
+	"fmt"
+	"slices"
 	"strings"
 
 	"github.com/charmbracelet/lipgloss"
+	"github.com/mistakenelf/teacup/filesystem"
+	"github.com/muesli/reflow/truncate"
 )
 
+// columnValue returns the text rendered in a detail column for an item.
+func (m Model) columnValue(file DirectoryItem, column Column) string {
+	switch column {
+	case SizeColumn:
+		if file.isDirectory {
+			return "-"
+		}
+
+		return FormatSize(file.size, m.sizeFormat)
+	case PermissionsColumn:
+		return file.mode.String()
+	case OwnerColumn:
+		if file.owner == "" {
+			return "-"
+		}
+
+		return file.owner + " " + file.group
+	case ModifiedColumn:
+		return file.modTime.Format(m.timeFormat)
+	case LinkTargetColumn:
+		if file.linkTarget == "" {
+			return ""
+		}
+
+		return "→ " + file.linkTarget
+	}
+
+	return ""
+}
+
+// visibleFiles returns the items within the visible window.
+func (m Model) visibleFiles() []DirectoryItem {
+	start := min(m.min, len(m.files))
+	end := max(min(m.max+1, len(m.files)), start)
+
+	return m.files[start:end]
+}
+
+// columnWidths returns the width of each detail column based on the visible items.
+func (m Model) columnWidths() []int {
+	widths := make([]int, len(m.columns))
+
+	for _, file := range m.visibleFiles() {
+		for c, column := range m.columns {
+			widths[c] = max(widths[c], lipgloss.Width(m.columnValue(file, column)))
+		}
+	}
+
+	return widths
+}
+
+// highlightMatches renders a name, highlighting the runes matched by the filter.
+func highlightMatches(name string, matches []int, style lipgloss.Style) string {
+	if len(matches) == 0 {
+		return style.Render(name)
+	}
+
+	var highlighted strings.Builder
+
+	matchStyle := style.Copy().Inherit(filterMatchStyle)
+	runes := []rune(name)
+	next := 0
+
+	for i := 0; i < len(runes); {
+		isMatch := next < len(matches) && matches[next] == i
+		end := i
+
+		// Render runs of matched and unmatched runes together.
+		for end < len(runes) && (next < len(matches) && matches[next] == end) == isMatch {
+			if isMatch {
+				next++
+			}
+
+			end++
+		}
+
+		if isMatch {
+			highlighted.WriteString(matchStyle.Render(string(runes[i:end])))
+		} else {
+			highlighted.WriteString(style.Render(string(runes[i:end])))
+		}
+
+		i = end
+	}
+
+	return highlighted.String()
+}
+
+// renderItem renders a single row of the listing.
+func (m Model) renderItem(file DirectoryItem, selected bool, columnWidths []int) string {
+	var row strings.Builder
+
+	// Reserve a column for marks only while something is marked.
+	if len(m.selection) > 0 {
+		if m.IsSelected(file.path) {
+			row.WriteString(markedItemStyle.Render("▌"))
+		} else {
+			row.WriteString(" ")
+		}
+	}
+
+	// Reserve a column for git status only inside a working tree.
+	var gitStyle lipgloss.Style
+
+	status := m.gitStatus(file)
+	if m.gitStatuses != nil {
+		var marker string
+		marker, gitStyle = gitStatusMarker(status)
+		row.WriteString(gitStyle.Render(marker) + " ")
+	}
+
+	row.WriteString(treeGuideStyle.Render(file.guide))
+
+	name := file.name
+	if m.showIcons {
+		// The icon color is a raw escape sequence so it
+		// needs to be reset after the glyph.
+		row.WriteString(fmt.Sprintf("%s%s\033[0m ", file.iconColor, file.icon))
+		name += file.indicator
+	}
+
+	nameStyle := lipgloss.NewStyle()
+	if status != filesystem.GitUnmodified {
+		nameStyle = gitStyle
+	}
+
+	if file.isBrokenLink {
+		nameStyle = brokenLinkStyle
+	}
+
+	if m.IsSelected(file.path) {
+		nameStyle = markedItemStyle
+	}
+
+	if selected {
+		nameStyle = selectedItemStyle
+	}
+
+	name = highlightMatches(name, file.matches, nameStyle)
+
+	// Link targets are shown after the name unless they have their own column.
+	if file.linkTarget != "" && !(m.showDetails && slices.Contains(m.columns, LinkTargetColumn)) {
+		targetStyle := linkTargetStyle
+		if file.isBrokenLink {
+			targetStyle = brokenLinkStyle
+		}
+
+		name += targetStyle.Render(" → " + file.linkTarget)
+	}
+
+	if m.showDetails && m.width > 0 {
+		nameWidth := m.width - lipgloss.Width(row.String())
+		for _, width := range columnWidths {
+			nameWidth -= width + 1
+		}
+
+		nameWidth = max(nameWidth, 1)
+		name = truncate.StringWithTail(name, uint(nameWidth), "…")
+		name += strings.Repeat(" ", nameWidth-lipgloss.Width(name))
+	}
+
+	row.WriteString(name)
+
+	if m.showDetails {
+		for c, column := range m.columns {
+			value := m.columnValue(file, column)
+			padding := strings.Repeat(" ", columnWidths[c]-lipgloss.Width(value))
+
+			// Sizes are right aligned so that the units line up.
+			if column == SizeColumn {
+				value = padding + value
+			} else {
+				value += padding
+			}
+
+			row.WriteString(" " + detailsStyle.Render(value))
+		}
+	}
+
+	if m.width > 0 {
+		return truncate.String(row.String(), uint(m.width))
+	}
+
+	return row.String()
+}
+
 func (m Model) View() string {
 	var fileList strings.Builder
 
-	for i, file := range m.files {
-		if i < m.min || i > m.max {
-			continue
+	var columnWidths []int
+	if m.showDetails {
+		columnWidths = m.columnWidths()
+	}
+
+	// Pickers and the trash are shown in place of the listing.
+	var overlay []string
+
+	switch {
+	case m.isPicking():
+		overlay = m.pickerView()
+	case m.isTrashView():
+		overlay = m.trashView()
+	}
+
+	rows := 0
+	for _, row := range overlay {
+		if rows >= max(m.listHeight(), 1) {
+			break
 		}
 
-		if i == m.cursor {
-			fileList.WriteString(selectedItemStyle.Render(file.name) + "\n")
-			// fileList.WriteString(selectedItemStyle.Render(file.details) + "\n\n")
-		} else {
-			fileList.WriteString(file.name + "\n")
-			// fileList.WriteString(file.details + "\n\n")
+		if m.width > 0 {
+			row = truncate.String(row, uint(m.width))
 		}
+
+		fileList.WriteString(row + "\n")
+		rows++
+	}
+
+	for i := m.min; i <= m.max && i < len(m.files) && overlay == nil; i++ {
+		fileList.WriteString(m.renderItem(m.files[i], i == m.cursor, columnWidths) + "\n")
+		rows++
 	}
 
-	for i := lipgloss.Height(fileList.String()); i <= m.height; i++ {
+	for ; rows < m.listHeight(); rows++ {
 		fileList.WriteRune('\n')
 	}
 
+	if footer := m.footer(); footer != "" {
+		fileList.WriteString(footer)
+	}
+
 	return fileList.String()
 }
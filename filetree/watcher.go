@@ -0,0 +1,180 @@
+package filetree
+
+import (
+	"os"
+	"sync"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+const (
+	watchDebounce     = 100 * time.Millisecond
+	watchMaxWait      = 10 * watchDebounce
+	watchPollInterval = time.Second
+)
+
+type watcherStartedMsg struct {
+	watcher *watcher
+}
+
+type directoryChangedOnDiskMsg struct {
+	watcher *watcher
+}
+
+// watcher reports changes made to a directory by other processes. Bursts of
+// changes are debounced into a single event.
+type watcher struct {
+	directory string
+	changes   chan struct{}
+	events    chan struct{}
+	done      chan struct{}
+	closeOnce sync.Once
+	closers   []func()
+}
+
+// newWatcher starts watching a directory, using the native file notification
+// API of the platform and falling back to polling when it is not available.
+func newWatcher(directory string) *watcher {
+	w := &watcher{
+		directory: directory,
+		changes:   make(chan struct{}, 1),
+		events:    make(chan struct{}, 1),
+		done:      make(chan struct{}),
+	}
+
+	if err := w.watchNative(); err != nil {
+		go w.poll()
+	}
+
+	go w.debounce()
+
+	return w
+}
+
+// notify records a raw change without blocking.
+func (w *watcher) notify() {
+	select {
+	case w.changes <- struct{}{}:
+	default:
+	}
+}
+
+// debounce waits for changes to settle before sending a single event. Changes
+// which never settle, such as a file being written to continuously, still send
+// an event once watchMaxWait has passed since the first of them.
+func (w *watcher) debounce() {
+	timer := time.NewTimer(watchDebounce)
+	timer.Stop()
+
+	defer timer.Stop()
+
+	var deadline time.Time
+
+	for {
+		select {
+		case <-w.done:
+			return
+		case <-w.changes:
+			if deadline.IsZero() {
+				deadline = time.Now().Add(watchMaxWait)
+			}
+
+			timer.Reset(min(watchDebounce, time.Until(deadline)))
+		case <-timer.C:
+			deadline = time.Time{}
+
+			select {
+			case w.events <- struct{}{}:
+			default:
+			}
+		}
+	}
+}
+
+// snapshot returns the name, size and modification time of each entry in the directory.
+func (w *watcher) snapshot() map[string]string {
+	entries, err := os.ReadDir(w.directory)
+	if err != nil {
+		return nil
+	}
+
+	snapshot := make(map[string]string, len(entries))
+	for _, entry := range entries {
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+
+		snapshot[entry.Name()] = info.ModTime().String() + info.Mode().String() + ConvertBytesToSizeString(info.Size())
+	}
+
+	return snapshot
+}
+
+// poll compares snapshots of the directory on an interval.
+func (w *watcher) poll() {
+	ticker := time.NewTicker(watchPollInterval)
+	defer ticker.Stop()
+
+	previous := w.snapshot()
+
+	for {
+		select {
+		case <-w.done:
+			return
+		case <-ticker.C:
+			current := w.snapshot()
+			if !sameSnapshot(previous, current) {
+				w.notify()
+			}
+
+			previous = current
+		}
+	}
+}
+
+// sameSnapshot reports if two directory snapshots are equal.
+func sameSnapshot(a, b map[string]string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for name, state := range a {
+		if b[name] != state {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Close stops the watcher and all of its goroutines.
+func (w *watcher) Close() {
+	w.closeOnce.Do(func() {
+		close(w.done)
+
+		for _, closer := range w.closers {
+			closer()
+		}
+	})
+}
+
+// watchDirectoryCmd starts watching a directory for changes.
+func watchDirectoryCmd(directory string) tea.Cmd {
+	return func() tea.Msg {
+		return watcherStartedMsg{watcher: newWatcher(directory)}
+	}
+}
+
+// waitForChangeCmd waits for the next change reported by a watcher.
+func waitForChangeCmd(w *watcher) tea.Cmd {
+	return func() tea.Msg {
+		select {
+		case <-w.events:
+			return directoryChangedOnDiskMsg{watcher: w}
+		case <-w.done:
+			return nil
+		}
+	}
+}
@@ -0,0 +1,47 @@
+//go:build linux
+
+package filetree
+
+import (
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+const inotifyMask = unix.IN_CREATE | unix.IN_DELETE | unix.IN_MODIFY | unix.IN_ATTRIB |
+	unix.IN_MOVED_FROM | unix.IN_MOVED_TO | unix.IN_DELETE_SELF | unix.IN_MOVE_SELF
+
+// watchNative watches the directory using inotify.
+func (w *watcher) watchNative() error {
+	fd, err := unix.InotifyInit1(unix.IN_CLOEXEC | unix.IN_NONBLOCK)
+	if err != nil {
+		return err
+	}
+
+	if _, err := unix.InotifyAddWatch(fd, w.directory, inotifyMask); err != nil {
+		_ = unix.Close(fd)
+
+		return err
+	}
+
+	// Wrapping the non blocking descriptor in a file lets the runtime poller
+	// wait on it, so closing the file unblocks the pending read.
+	file := os.NewFile(uintptr(fd), "inotify")
+	w.closers = append(w.closers, func() {
+		_ = file.Close()
+	})
+
+	go func() {
+		buffer := make([]byte, unix.SizeofInotifyEvent*64+unix.NAME_MAX+1)
+
+		for {
+			if _, err := file.Read(buffer); err != nil {
+				return
+			}
+
+			w.notify()
+		}
+	}()
+
+	return nil
+}
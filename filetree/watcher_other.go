@@ -0,0 +1,10 @@
+//go:build !linux
+
+package filetree
+
+import "errors"
+
+// watchNative is not supported on this platform so the watcher falls back to polling.
+func (w *watcher) watchNative() error {
+	return errors.New("native file watching is not supported")
+}
@@ -80,7 +80,11 @@ func GetIcon(name, ext, indicator string) (icon, color string) {
 			i = IconDef["exe"]
 		}
 
-		i.MakeExe()
+		// Copy the icon so that marking it as executable does
+		// not change the shared icon set.
+		exe := *i
+		exe.MakeExe()
+		i = &exe
 	}
 
 	return i.GetGlyph(), i.GetColor(1)
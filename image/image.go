@@ -4,7 +4,6 @@ package image
 
 import (
 	"image"
-	"os"
 	"path/filepath"
 	"strings"
 
@@ -13,6 +12,7 @@ import (
 	"github.com/charmbracelet/lipgloss"
 	"github.com/disintegration/imaging"
 	"github.com/lucasb-eyer/go-colorful"
+	"github.com/mistakenelf/teacup/filesystem"
 )
 
 type convertImageToStringMsg string
@@ -47,13 +47,15 @@ func ToString(width int, img image.Image) string {
 }
 
 // convertImageToStringCmd redraws the image based on the width provided.
-func convertImageToStringCmd(width int, filename string) tea.Cmd {
+func convertImageToStringCmd(fsys filesystem.FS, width int, filename string) tea.Cmd {
 	return func() tea.Msg {
-		imageContent, err := os.Open(filepath.Clean(filename))
+		imageContent, err := fsys.Open(filepath.Clean(filename))
 		if err != nil {
 			return errorMsg(err)
 		}
 
+		defer imageContent.Close()
+
 		img, _, err := image.Decode(imageContent)
 		if err != nil {
 			return errorMsg(err)
@@ -72,6 +74,7 @@ type Model struct {
 	Borderless  bool
 	FileName    string
 	ImageString string
+	FileSystem  filesystem.FS
 }
 
 // New creates a new instance of code.
@@ -79,8 +82,9 @@ func New(active, borderless bool, borderColor lipgloss.AdaptiveColor) Model {
 	viewPort := viewport.New(0, 0)
 
 	return Model{
-		Viewport: viewPort,
-		Active:   active,
+		Viewport:   viewPort,
+		Active:     active,
+		FileSystem: filesystem.OSFS{},
 	}
 }
 
@@ -94,7 +98,12 @@ func (m Model) Init() tea.Cmd {
 func (m *Model) SetFileName(filename string) tea.Cmd {
 	m.FileName = filename
 
-	return convertImageToStringCmd(m.Viewport.Width, filename)
+	return convertImageToStringCmd(m.FileSystem, m.Viewport.Width, filename)
+}
+
+// SetFileSystem sets the backend files are read from.
+func (m *Model) SetFileSystem(fsys filesystem.FS) {
+	m.FileSystem = fsys
 }
 
 // SetSize sets the size of the bubble.
@@ -103,7 +112,7 @@ func (m *Model) SetSize(w, h int) tea.Cmd {
 	m.Viewport.Height = h
 
 	if m.FileName != "" {
-		return convertImageToStringCmd(m.Viewport.Width, m.FileName)
+		return convertImageToStringCmd(m.FileSystem, m.Viewport.Width, m.FileName)
 	}
 
 	return nil
@@ -17,9 +17,10 @@ type errorMsg error
 
 // Model represents the properties of a code bubble.
 type Model struct {
-	Viewport viewport.Model
-	Active   bool
-	FileName string
+	Viewport   viewport.Model
+	Active     bool
+	FileName   string
+	FileSystem filesystem.FS
 }
 
 // RenderMarkdown renders the markdown content with glamour.
@@ -44,9 +45,9 @@ func RenderMarkdown(width int, content string) (string, error) {
 }
 
 // renderMarkdownCmd renders text as pretty markdown.
-func renderMarkdownCmd(width int, filename string) tea.Cmd {
+func renderMarkdownCmd(fsys filesystem.FS, width int, filename string) tea.Cmd {
 	return func() tea.Msg {
-		content, err := filesystem.ReadFileContent(filename)
+		content, err := filesystem.ReadFileContentFS(fsys, filename)
 		if err != nil {
 			return errorMsg(err)
 		}
@@ -65,8 +66,9 @@ func New(active bool) Model {
 	viewPort := viewport.New(0, 0)
 
 	return Model{
-		Viewport: viewPort,
-		Active:   active,
+		Viewport:   viewPort,
+		Active:     active,
+		FileSystem: filesystem.OSFS{},
 	}
 }
 
@@ -80,7 +82,12 @@ func (m Model) Init() tea.Cmd {
 func (m *Model) SetFileName(filename string) tea.Cmd {
 	m.FileName = filename
 
-	return renderMarkdownCmd(m.Viewport.Width, filename)
+	return renderMarkdownCmd(m.FileSystem, m.Viewport.Width, filename)
+}
+
+// SetFileSystem sets the backend files are read from.
+func (m *Model) SetFileSystem(fsys filesystem.FS) {
+	m.FileSystem = fsys
 }
 
 // SetSize sets the size of the bubble.
@@ -89,7 +96,7 @@ func (m *Model) SetSize(w, h int) tea.Cmd {
 	m.Viewport.Height = h
 
 	if m.FileName != "" {
-		return renderMarkdownCmd(m.Viewport.Width, m.FileName)
+		return renderMarkdownCmd(m.FileSystem, m.Viewport.Width, m.FileName)
 	}
 
 	return nil
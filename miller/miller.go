@@ -0,0 +1,314 @@
+// Package miller implements a bubble which browses directories in miller
+// columns, showing the parent directory, the current directory and a
+// preview of the highlighted item side by side.
+package miller
+
+import (
+	"path/filepath"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/mistakenelf/teacup/code"
+	"github.com/mistakenelf/teacup/filetree"
+	"github.com/mistakenelf/teacup/image"
+	"github.com/mistakenelf/teacup/markdown"
+	"github.com/mistakenelf/teacup/pdf"
+)
+
+// columnGap is the number of blank cells between columns.
+const columnGap = 1
+
+var noteStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("245"))
+
+// Model represents the properties of a miller columns bubble.
+type Model struct {
+	parent          filetree.Model
+	current         filetree.Model
+	directory       filetree.Model
+	code            code.Model
+	markdown        markdown.Model
+	image           image.Model
+	pdf             pdf.Model
+	preview         previewKind
+	previewPath     string
+	previewNote     string
+	previewSeq      int
+	parentDirectory string
+	archivePath     string
+	ratios          [3]int
+	width           int
+	height          int
+	originX         int
+	originY         int
+}
+
+// New creates a new instance of miller columns, the options
+// configure the filetree of every column.
+func New(opts ...filetree.Option) Model {
+	parent := filetree.New(opts...)
+	directory := filetree.New(opts...)
+
+	// Only the current column watches its directory and stores bookmarks.
+	for _, column := range []*filetree.Model{&parent, &directory} {
+		column.SetIsActive(false)
+		column.SetWatch(false)
+		column.SetBookmarks(nil)
+		column.SetShowGitStatus(false)
+	}
+
+	return Model{
+		parent:    parent,
+		current:   filetree.New(opts...),
+		directory: directory,
+		code:      code.New(false),
+		markdown:  markdown.New(false),
+		image:     image.New(false, true, lipgloss.AdaptiveColor{}),
+		pdf:       pdf.New(false),
+		ratios:    [3]int{1, 3, 4},
+	}
+}
+
+// Init initializes the miller columns bubble.
+func (m Model) Init() tea.Cmd {
+	return m.current.Init()
+}
+
+// SetRatios sets the width of the parent, current and preview columns relative
+// to each other. A column is hidden when its ratio is zero.
+func (m *Model) SetRatios(parent, current, preview int) tea.Cmd {
+	m.ratios = [3]int{max(parent, 0), max(current, 0), max(preview, 0)}
+
+	return m.SetSize(m.width, m.height)
+}
+
+// Ratios returns the width of the parent, current and preview columns relative to each other.
+func (m Model) Ratios() (parent, current, preview int) {
+	return m.ratios[0], m.ratios[1], m.ratios[2]
+}
+
+// columnWidths returns the width of each column, the last visible column
+// takes the cells left over by rounding.
+func (m Model) columnWidths() [3]int {
+	var widths [3]int
+
+	total, columns, last := 0, 0, 0
+
+	for i, ratio := range m.ratios {
+		if ratio > 0 {
+			total += ratio
+			columns++
+			last = i
+		}
+	}
+
+	if total == 0 {
+		return widths
+	}
+
+	available := max(m.width-(columns-1)*columnGap, 0)
+	remaining := available
+
+	for i, ratio := range m.ratios {
+		widths[i] = available * ratio / total
+		remaining -= widths[i]
+	}
+
+	widths[last] += remaining
+
+	return widths
+}
+
+// SetSize sets the size of the bubble.
+func (m *Model) SetSize(w, h int) tea.Cmd {
+	var cmds []tea.Cmd
+
+	m.width = w
+	m.height = h
+
+	widths := m.columnWidths()
+
+	m.parent.SetSize(widths[0], h)
+	m.current.SetSize(widths[1], h)
+	m.directory.SetSize(widths[2], h)
+	m.code.SetSize(widths[2], h)
+	m.pdf.SetSize(widths[2], h)
+	m.setOrigin()
+
+	// Markdown and images are rendered for the width of the
+	// preview, only the one being previewed is rendered again.
+	markdownCmd := m.markdown.SetSize(widths[2], h)
+	imageCmd := m.image.SetSize(widths[2], h)
+
+	switch m.preview {
+	case markdownPreview:
+		cmds = append(cmds, m.previewCmd(markdownCmd))
+	case imagePreview:
+		cmds = append(cmds, m.previewCmd(imageCmd))
+	}
+
+	return tea.Batch(cmds...)
+}
+
+// SetOrigin sets the position of the top left corner of the bubble on the screen,
+// so that mouse events can be mapped to rows when it is not drawn at the top left.
+func (m *Model) SetOrigin(x, y int) {
+	m.originX = x
+	m.originY = y
+	m.setOrigin()
+}
+
+// setOrigin places the current column next to the parent column.
+func (m *Model) setOrigin() {
+	x := m.originX
+	if width := m.columnWidths()[0]; width > 0 {
+		x += width + columnGap
+	}
+
+	m.current.SetOrigin(x, m.originY)
+}
+
+// SetSyntaxTheme sets the syntax theme of previewed code.
+func (m *Model) SetSyntaxTheme(theme string) {
+	m.code.SetSyntaxTheme(theme)
+}
+
+// SelectedItem returns the highlighted item of the current column.
+func (m Model) SelectedItem() (filetree.DirectoryItem, bool) {
+	return m.current.SelectedItem()
+}
+
+// CurrentDirectory returns the directory listed in the current column.
+func (m Model) CurrentDirectory() string {
+	return m.current.CurrentDirectory()
+}
+
+// ShortHelp returns the bindings of the current column for the short help view.
+func (m Model) ShortHelp() []key.Binding {
+	return m.current.ShortHelp()
+}
+
+// FullHelp returns the bindings of the current column for the full help view.
+func (m Model) FullHelp() [][]key.Binding {
+	return m.current.FullHelp()
+}
+
+// Close stops listing and watching directories, it should be called
+// once the bubble is no longer in use.
+func (m *Model) Close() {
+	m.parent.Close()
+	m.current.Close()
+	m.directory.Close()
+}
+
+// syncParent lists the parent of the current directory, highlighting the current directory.
+func (m *Model) syncParent(msg filetree.DirectoryChangedMsg) tea.Cmd {
+	// The other columns browse the same archive as the current column. The
+	// listing returned when switching is not needed as a directory is set after.
+	if archivePath := m.current.ArchivePath(); archivePath != m.archivePath {
+		m.archivePath = archivePath
+		m.parent.SetFileSystem(msg.FileSystem)
+		m.directory.SetFileSystem(msg.FileSystem)
+	}
+
+	m.parentDirectory = filepath.Dir(msg.Directory)
+
+	// The root has no parent.
+	if m.parentDirectory == msg.Directory {
+		m.parentDirectory = ""
+
+		return nil
+	}
+
+	return m.parent.SetDirectory(m.parentDirectory, msg.Directory)
+}
+
+// Update handles updating the UI of a miller columns bubble.
+func (m Model) Update(msg tea.Msg) (Model, tea.Cmd) {
+	var (
+		cmd  tea.Cmd
+		cmds []tea.Cmd
+	)
+
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		return m, m.SetSize(msg.Width, msg.Height)
+	case previewMsg:
+		if msg.seq != m.previewSeq {
+			return m, nil
+		}
+
+		return m, m.updatePreview(msg.msg)
+	case filetree.DirectoryChangedMsg:
+		// Listings which finished after a newer one are listed again,
+		// so that each column stays in sync with the current column.
+		switch {
+		case msg.ID == m.current.ID():
+			return m, m.syncParent(msg)
+		case msg.ID == m.parent.ID() && msg.Directory != m.parentDirectory && m.parentDirectory != "":
+			return m, m.parent.SetDirectory(m.parentDirectory, m.current.CurrentDirectory())
+		case msg.ID == m.directory.ID() && msg.Directory != m.previewPath && m.preview == directoryPreview:
+			return m, m.directory.SetDirectory(m.previewPath, "")
+		}
+
+		return m, nil
+	case filetree.FileHighlightedMsg:
+		if msg.ID == m.current.ID() {
+			return m, m.setPreview(msg.Item, msg.FileSystem)
+		}
+
+		return m, nil
+	case tea.KeyMsg, tea.MouseMsg:
+		m.current, cmd = m.current.Update(msg)
+
+		return m, cmd
+	}
+
+	// Each filetree only handles its own messages.
+	m.parent, cmd = m.parent.Update(msg)
+	cmds = append(cmds, cmd)
+
+	m.current, cmd = m.current.Update(msg)
+	cmds = append(cmds, cmd)
+
+	m.directory, cmd = m.directory.Update(msg)
+	cmds = append(cmds, cmd)
+
+	return m, tea.Batch(cmds...)
+}
+
+// View returns a string representation of the miller columns bubble.
+func (m Model) View() string {
+	var columns []string
+
+	parentView := ""
+	if m.parentDirectory != "" {
+		parentView = m.parent.View()
+	}
+
+	widths := m.columnWidths()
+
+	for i, view := range []string{parentView, m.current.View(), m.previewView()} {
+		if widths[i] == 0 {
+			continue
+		}
+
+		style := lipgloss.NewStyle().
+			Width(widths[i]).
+			MaxWidth(widths[i]).
+			Height(m.height).
+			MaxHeight(m.height)
+
+		if len(columns) > 0 {
+			style = style.
+				Width(widths[i] + columnGap).
+				MaxWidth(widths[i] + columnGap).
+				PaddingLeft(columnGap)
+		}
+
+		columns = append(columns, style.Render(strings.TrimSuffix(view, "\n")))
+	}
+
+	return lipgloss.JoinHorizontal(lipgloss.Top, columns...)
+}
@@ -0,0 +1,210 @@
+package miller
+
+import (
+	"bytes"
+	"io"
+	"path/filepath"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/mistakenelf/teacup/filesystem"
+	"github.com/mistakenelf/teacup/filetree"
+)
+
+const (
+	// maxTextPreviewSize is the largest file, in bytes, previewed as text.
+	maxTextPreviewSize = 1 << 20
+
+	// binarySniffSize is the number of bytes read to decide if a file is binary.
+	binarySniffSize = 512
+)
+
+// previewKind represents the bubble used to preview the highlighted item.
+type previewKind int
+
+const (
+	noPreview previewKind = iota
+	directoryPreview
+	codePreview
+	markdownPreview
+	imagePreview
+	pdfPreview
+)
+
+// previewMsg is a message for the preview of an item, messages for items
+// which are no longer highlighted are dropped.
+type previewMsg struct {
+	seq int
+	msg tea.Msg
+}
+
+// previewUnavailableMsg is sent when a file can not be previewed, with the reason why.
+type previewUnavailableMsg string
+
+// previewKindOf returns the bubble used to preview an item based on its type.
+func previewKindOf(item filetree.DirectoryItem) previewKind {
+	switch {
+	case item.Path() == "" || item.IsBrokenLink():
+		return noPreview
+	case item.IsDirectory():
+		return directoryPreview
+	case filesystem.IsArchive(item.Name()):
+		return noPreview
+	}
+
+	switch strings.ToLower(item.Extension()) {
+	case ".md", ".markdown":
+		return markdownPreview
+	case ".png", ".jpg", ".jpeg", ".gif", ".bmp", ".tif", ".tiff":
+		return imagePreview
+	case ".pdf":
+		return pdfPreview
+	}
+
+	return codePreview
+}
+
+// checkFileCmd runs a preview command once the file is known to be a regular
+// file, text previews are also only shown for small files which are not binary.
+func checkFileCmd(fsys filesystem.FS, name string, text bool, next tea.Cmd) tea.Cmd {
+	return func() tea.Msg {
+		info, err := fsys.Stat(name)
+		if err != nil {
+			// The preview reports the error.
+			return next()
+		}
+
+		if !info.Mode().IsRegular() {
+			return previewUnavailableMsg("Not a regular file")
+		}
+
+		if text {
+			if info.Size() > maxTextPreviewSize {
+				return previewUnavailableMsg("Too large to preview")
+			}
+
+			if isBinary(fsys, name) {
+				return previewUnavailableMsg("Binary file")
+			}
+		}
+
+		return next()
+	}
+}
+
+// isBinary reports if the start of a file contains a null byte,
+// which text files do not.
+func isBinary(fsys filesystem.FS, name string) bool {
+	file, err := fsys.Open(filepath.Clean(name))
+	if err != nil {
+		return false
+	}
+
+	defer file.Close()
+
+	head := make([]byte, binarySniffSize)
+	n, err := io.ReadFull(file, head)
+
+	if err != nil && n == 0 {
+		return false
+	}
+
+	return bytes.IndexByte(head[:n], 0) >= 0
+}
+
+// previewCmd tags the messages of a preview command with the highlighted item.
+func (m Model) previewCmd(cmd tea.Cmd) tea.Cmd {
+	if cmd == nil {
+		return nil
+	}
+
+	seq := m.previewSeq
+
+	return func() tea.Msg {
+		return previewMsg{seq: seq, msg: cmd()}
+	}
+}
+
+// setPreview previews the highlighted item of the current column.
+func (m *Model) setPreview(item filetree.DirectoryItem, fsys filesystem.FS) tea.Cmd {
+	var cmd tea.Cmd
+
+	// Messages for the previous item are dropped from now on.
+	m.previewSeq++
+	m.previewPath = item.Path()
+	m.previewNote = ""
+	m.preview = previewKindOf(item)
+
+	switch m.preview {
+	case directoryPreview:
+		// Directories are listed by a filetree, its messages are routed by id.
+		return m.directory.SetDirectory(item.Path(), "")
+	case codePreview:
+		m.code.SetFileSystem(fsys)
+		m.code.GotoTop()
+		cmd = checkFileCmd(fsys, item.Path(), true, m.code.SetFileName(item.Path()))
+	case markdownPreview:
+		m.markdown.SetFileSystem(fsys)
+		m.markdown.GotoTop()
+		cmd = checkFileCmd(fsys, item.Path(), true, m.markdown.SetFileName(item.Path()))
+	case imagePreview:
+		m.image.SetFileSystem(fsys)
+		m.image.GotoTop()
+		cmd = checkFileCmd(fsys, item.Path(), false, m.image.SetFileName(item.Path()))
+	case pdfPreview:
+		m.pdf.SetFileSystem(fsys)
+		m.pdf.GotoTop()
+		cmd = checkFileCmd(fsys, item.Path(), false, m.pdf.SetFileName(item.Path()))
+	}
+
+	return m.previewCmd(cmd)
+}
+
+// updatePreview passes a message to the bubble previewing the highlighted item.
+func (m *Model) updatePreview(msg tea.Msg) tea.Cmd {
+	var cmd tea.Cmd
+
+	if note, ok := msg.(previewUnavailableMsg); ok {
+		m.previewNote = string(note)
+
+		return nil
+	}
+
+	switch m.preview {
+	case codePreview:
+		m.code, cmd = m.code.Update(msg)
+	case markdownPreview:
+		m.markdown, cmd = m.markdown.Update(msg)
+	case imagePreview:
+		m.image, cmd = m.image.Update(msg)
+	case pdfPreview:
+		m.pdf, cmd = m.pdf.Update(msg)
+	}
+
+	return m.previewCmd(cmd)
+}
+
+// previewView renders the preview of the highlighted item.
+func (m Model) previewView() string {
+	if m.previewNote != "" {
+		return noteStyle.Render(m.previewNote)
+	}
+
+	switch m.preview {
+	case directoryPreview:
+		// A listing for another directory may still be shown while it is read.
+		if m.directory.CurrentDirectory() == m.previewPath {
+			return m.directory.View()
+		}
+	case codePreview:
+		return m.code.View()
+	case markdownPreview:
+		return m.markdown.View()
+	case imagePreview:
+		return m.image.View()
+	case pdfPreview:
+		return m.pdf.View()
+	}
+
+	return ""
+}
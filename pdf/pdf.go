@@ -5,11 +5,13 @@ package pdf
 import (
 	"bytes"
 	"errors"
+	"io"
 
 	"github.com/charmbracelet/bubbles/viewport"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
 	"github.com/ledongthuc/pdf"
+	"github.com/mistakenelf/teacup/filesystem"
 )
 
 type renderPDFMsg string
@@ -17,23 +19,43 @@ type errorMsg error
 
 // Model represents the properties of a pdf bubble.
 type Model struct {
-	Viewport viewport.Model
-	Active   bool
-	FileName string
+	Viewport   viewport.Model
+	Active     bool
+	FileName   string
+	FileSystem filesystem.FS
 }
 
 // readPdf reads a PDF file given a name.
-func readPdf(name string) (string, error) {
-	file, reader, err := pdf.Open(name)
+func readPdf(fsys filesystem.FS, name string) (string, error) {
+	file, err := fsys.Open(name)
 	if err != nil {
 		return "", errors.Unwrap(err)
 	}
 
-	defer func() {
-		if e := file.Close(); e != nil {
-			err = e
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return "", errors.Unwrap(err)
+	}
+
+	// Read the file into memory when the backend can not seek within it.
+	readerAt, ok := file.(io.ReaderAt)
+	size := info.Size()
+
+	if !ok {
+		data, err := fsys.ReadFile(name)
+		if err != nil {
+			return "", errors.Unwrap(err)
 		}
-	}()
+
+		readerAt, size = bytes.NewReader(data), int64(len(data))
+	}
+
+	reader, err := pdf.NewReader(readerAt, size)
+	if err != nil {
+		return "", errors.Unwrap(err)
+	}
 
 	buf := new(bytes.Buffer)
 	buffer, err := reader.GetPlainText()
@@ -51,9 +73,9 @@ func readPdf(name string) (string, error) {
 }
 
 // renderPDFCmd reads the content of a PDF and returns its content as a string.
-func renderPDFCmd(filename string) tea.Cmd {
+func renderPDFCmd(fsys filesystem.FS, filename string) tea.Cmd {
 	return func() tea.Msg {
-		pdfContent, err := readPdf(filename)
+		pdfContent, err := readPdf(fsys, filename)
 		if err != nil {
 			return errorMsg(err)
 		}
@@ -67,8 +89,9 @@ func New(active bool) Model {
 	viewPort := viewport.New(0, 0)
 
 	return Model{
-		Viewport: viewPort,
-		Active:   active,
+		Viewport:   viewPort,
+		Active:     active,
+		FileSystem: filesystem.OSFS{},
 	}
 }
 
@@ -82,7 +105,12 @@ func (m Model) Init() tea.Cmd {
 func (m *Model) SetFileName(filename string) tea.Cmd {
 	m.FileName = filename
 
-	return renderPDFCmd(filename)
+	return renderPDFCmd(m.FileSystem, filename)
+}
+
+// SetFileSystem sets the backend files are read from.
+func (m *Model) SetFileSystem(fsys filesystem.FS) {
+	m.FileSystem = fsys
 }
 
 // SetSize sets the size of the bubble.